@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+	"github.com/gorilla/websocket"
+)
+
+// Replays a match log written with `superserveur -record` through the game engine
+// and checks that every round ends with the recorded scores.
+// With -serve, the replay is streamed over websocket so clients can watch it.
+func main() {
+	file := flag.String("file", "match.jsonl", "match log to replay")
+	serve := flag.String("serve", "", "websocket address to stream the replay on (e.g. :8081), empty to only verify")
+	speed := flag.Float64("speed", 1, "playback speed when serving (2 = twice as fast)")
+	flag.Parse()
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatal("open:", err)
+	}
+	rp, err := game.LoadReplay(f)
+	f.Close()
+	if err != nil {
+		log.Fatal("load:", err)
+	}
+
+	var onTick func(int64)
+	if *serve != "" {
+		if *speed <= 0 || rp.TPS <= 0 {
+			log.Fatal("serve needs a positive -speed and a log with a tick rate")
+		}
+		s := newSpectators(rp.Game)
+		http.HandleFunc("/ws", s.serveWS)
+		go func() { log.Fatal(http.ListenAndServe(*serve, nil)) }()
+		log.Println("[REPLAY] waiting for a spectator on", *serve+"/ws")
+		s.waitFirst()
+		period := time.Duration(float64(time.Second) / float64(rp.TPS) / *speed)
+		onTick = func(int64) { time.Sleep(period) }
+	}
+
+	if err := rp.Run(onTick); err != nil {
+		log.Fatal("[REPLAY] ", err)
+	}
+	fmt.Printf("replay OK: %d round(s) verified\n", rp.Rounds)
+	for _, s := range rp.Game.Scores() {
+		fmt.Printf("  %s (%s): %d\n", s.ID, s.Name, s.Score)
+	}
+}
+
+// spectators forwards the replayed game broadcasts to every connected websocket.
+type spectators struct {
+	g       *game.Game
+	mu      sync.Mutex
+	conns   map[*websocket.Conn]bool
+	joined  chan struct{}
+	onFirst sync.Once
+}
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+func newSpectators(g *game.Game) *spectators {
+	s := &spectators{g: g, conns: make(map[*websocket.Conn]bool), joined: make(chan struct{})}
+	go s.forward(g.StateBroadcast)
+	go s.forward(g.EventBroadcast)
+	return s
+}
+
+func (s *spectators) waitFirst() { <-s.joined }
+
+func (s *spectators) forward(ch chan []byte) {
+	for b := range ch {
+		s.mu.Lock()
+		for c := range s.conns {
+			c.SetWriteDeadline(time.Now().Add(time.Second))
+			if err := c.WriteMessage(websocket.TextMessage, b); err != nil {
+				c.Close()
+				delete(s.conns, c)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// serveWS registers a spectator. A "join" is answered with a join_ack so that
+// existing clients display the replay as a live game; inputs are ignored.
+func (s *spectators) serveWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("[REPLAY] upgrade:", err)
+		return
+	}
+	s.mu.Lock()
+	s.conns[conn] = true
+	s.mu.Unlock()
+	s.onFirst.Do(func() { close(s.joined) })
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		var m map[string]interface{}
+		if json.Unmarshal(message, &m) == nil && m["type"] == "join" {
+			ack := map[string]interface{}{"type": "join_ack", "id": "spectator", "pos": map[string]int{"x": -1, "y": -1}, "grid": map[string]int{"w": s.g.W, "h": s.g.H}}
+			b, _ := json.Marshal(ack)
+			s.mu.Lock()
+			conn.WriteMessage(websocket.TextMessage, b)
+			s.mu.Unlock()
+		}
+	}
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+	conn.Close()
+}
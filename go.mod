@@ -2,4 +2,4 @@ module github.com/dntelisa/SR-S9-Projet-Serveur
 
 go 1.24.9
 
-require github.com/gorilla/websocket v1.5.3 // indirect
+require github.com/gorilla/websocket v1.5.3
@@ -0,0 +1,305 @@
+// Package anticheat watches the inputs of each player for impossible or bot-like sequences,
+// keeps a suspicion score and decides when to shadow-flag or kick a player.
+package anticheat
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Config holds the thresholds of the rules.
+type Config struct {
+	Window            time.Duration // sliding window of the move rate rule
+	MaxMovesPerWindow int           // moves allowed in Window (MaxMovesPerTick x ticks per window)
+	CadenceSamples    int           // intervals looked at by the cadence rule
+	CadenceJitter     time.Duration // below this standard deviation, the cadence is machine-perfect
+	FlagScore         float64       // score at which the player is shadow-flagged
+	KickScore         float64       // score at which the player is kicked
+	DecayPerSecond    float64       // score forgotten per second
+}
+
+// DefaultConfig fits the default game: 20 ticks/s, 2 moves per tick.
+// The server fits the move rate to its own settings with SetMoveLimit.
+var DefaultConfig = Config{
+	Window:            time.Second,
+	MaxMovesPerWindow: 40,
+	CadenceSamples:    30,
+	CadenceJitter:     250 * time.Microsecond, // network and OS scheduling alone add more than that
+	FlagScore:         50,
+	KickScore:         100,
+	DecayPerSecond:    0.5,
+}
+
+// points added to the score by each rule
+const (
+	invalidPoints   = 20 // teleport request or unknown direction
+	ratePoints      = 2  // per move over the window limit
+	cadencePoints   = 10 // a full window of machine-perfect timing
+	notInGamePoints = 1  // move without a player in the game (a client may race its join_ack)
+)
+
+// Verdict tells the caller what to do with an input.
+type Verdict int
+
+const (
+	Accept Verdict = iota
+	Reject         // drop the input
+	Kick           // drop the input and disconnect the player
+)
+
+// Flag is a rule broken by a player.
+type Flag struct {
+	Time   time.Time `json:"time"`
+	Rule   string    `json:"rule"`
+	Detail string    `json:"detail"`
+}
+
+const maxFlags = 20 // flags kept per player, newest last
+
+// Entry is the suspicion record of a player session.
+type Entry struct {
+	Key      string    `json:"key"` // room/player ID
+	Name     string    `json:"name"`
+	Score    float64   `json:"score"`
+	Shadow   bool      `json:"shadow"` // shadow-flagged: plays on, but is left out of stats and ratings
+	Kicked   bool      `json:"kicked"`
+	Moves    int       `json:"moves"`
+	Flags    []Flag    `json:"flags"`
+	LastSeen time.Time `json:"last_seen"`
+	Left     bool      `json:"left"`
+}
+
+type record struct {
+	Entry
+	updated   time.Time   // last score decay
+	window    []time.Time // move times in the current window
+	lastMove  time.Time
+	intervals []time.Duration // time between moves, for the cadence rule
+	leftAt    time.Time       // end of the session
+}
+
+// keep is how long the record of a player who left stays in the report, and its
+// shadow flag in effect (a reconnection does not clear it).
+const keep = time.Hour
+
+// pruneEvery is how often the write paths drop the records kept long enough.
+const pruneEvery = time.Minute
+
+// Monitor tracks every player session.
+type Monitor struct {
+	mu      sync.Mutex
+	cfg     Config
+	records map[string]*record
+	pruned  time.Time // last prune
+}
+
+// New returns a monitor using cfg.
+func New(cfg Config) *Monitor {
+	return &Monitor{cfg: cfg, records: make(map[string]*record)}
+}
+
+// Default is the monitor used by the server.
+var Default = New(DefaultConfig)
+
+// SetMoveLimit fits the move rate rule to a game of tps ticks per second and maxMoves
+// moves per tick: what a player can make in the window, no more.
+func (m *Monitor) SetMoveLimit(tps, maxMoves int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cfg.MaxMovesPerWindow = int(time.Duration(tps*maxMoves) * m.cfg.Window / time.Second)
+}
+
+// Move checks a move of the player key (name for the report) received at now.
+// dir is the requested direction and absolute is true if the input asked for a position (x/y).
+func (m *Monitor) Move(key, name, dir string, absolute bool, now time.Time) Verdict {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pruneLocked(now)
+	r := m.recordLocked(key, name, now)
+	if r.Kicked {
+		return Kick
+	}
+	r.Moves++
+
+	switch {
+	case absolute:
+		m.flagLocked(r, now, invalidPoints, "invalid_input", "absolute position requested")
+		return m.verdictLocked(r, Reject)
+	case dir != "up" && dir != "down" && dir != "left" && dir != "right":
+		m.flagLocked(r, now, invalidPoints, "invalid_input", fmt.Sprintf("unknown direction %q", dir))
+		return m.verdictLocked(r, Reject)
+	}
+
+	// move rate over the sliding window
+	cut := now.Add(-m.cfg.Window)
+	i := 0
+	for i < len(r.window) && !r.window[i].After(cut) {
+		i++
+	}
+	r.window = append(r.window[i:], now)
+	if over := len(r.window) - m.cfg.MaxMovesPerWindow; over > 0 {
+		m.flagLocked(r, now, ratePoints, "move_rate", fmt.Sprintf("%d moves in %v", len(r.window), m.cfg.Window))
+		return m.verdictLocked(r, Reject)
+	}
+
+	// machine-perfect cadence: intervals too regular for a human
+	if !r.lastMove.IsZero() {
+		r.intervals = append(r.intervals, now.Sub(r.lastMove))
+	}
+	r.lastMove = now
+	if len(r.intervals) >= m.cfg.CadenceSamples {
+		mean, std := meanStd(r.intervals)
+		r.intervals = r.intervals[:0]
+		if std < m.cfg.CadenceJitter {
+			m.flagLocked(r, now, cadencePoints, "cadence", fmt.Sprintf("interval %v ± %v", mean.Round(time.Microsecond), std.Round(time.Microsecond)))
+		}
+	}
+	return m.verdictLocked(r, Accept)
+}
+
+// NotInGame records a move from a client with no player in the game (not joined yet,
+// or already removed). key is the client's, to Forget when it disconnects.
+func (m *Monitor) NotInGame(key, name string, now time.Time) Verdict {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pruneLocked(now)
+	r := m.recordLocked(key, name, now)
+	m.flagLocked(r, now, notInGamePoints, "not_in_game", "move without a player")
+	return m.verdictLocked(r, Reject)
+}
+
+// Leave marks the session as ended, its record stays in the report for a while.
+func (m *Monitor) Leave(key string) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if r, ok := m.records[key]; ok && !r.Left {
+		r.Left, r.leftAt = true, now
+	}
+	m.pruneLocked(now)
+}
+
+// Forget drops the record of key at once, e.g. the one of a client that disconnected.
+func (m *Monitor) Forget(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, key)
+}
+
+// Shadowed reports whether a session of name is shadow-flagged, the current one or
+// one that ended less than keep ago.
+func (m *Monitor) Shadowed(name string) bool {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.records {
+		if r.Name == name && r.Shadow && !m.expiredLocked(r, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// expiredLocked reports whether the session of r ended more than keep before now.
+func (m *Monitor) expiredLocked(r *record, now time.Time) bool {
+	return r.Left && now.Sub(r.leftAt) > keep
+}
+
+// pruneLocked drops the expired records, at most every pruneEvery.
+func (m *Monitor) pruneLocked(now time.Time) {
+	if now.Sub(m.pruned) < pruneEvery {
+		return
+	}
+	m.pruned = now
+	for key, r := range m.records {
+		if m.expiredLocked(r, now) {
+			delete(m.records, key)
+		}
+	}
+}
+
+// Report returns the sessions with a non-zero score or a flag, most suspicious first.
+func (m *Monitor) Report() []Entry {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]Entry, 0)
+	for key, r := range m.records {
+		if m.expiredLocked(r, now) {
+			delete(m.records, key)
+			continue
+		}
+		m.decayLocked(r, now)
+		if r.Score == 0 && len(r.Flags) == 0 {
+			continue
+		}
+		e := r.Entry
+		e.Flags = append([]Flag(nil), r.Flags...)
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Score != list[j].Score {
+			return list[i].Score > list[j].Score
+		}
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
+
+func (m *Monitor) recordLocked(key, name string, now time.Time) *record {
+	r, ok := m.records[key]
+	if !ok {
+		r = &record{Entry: Entry{Key: key}, updated: now}
+		m.records[key] = r
+	}
+	if name != "" {
+		r.Name = name
+	}
+	r.LastSeen = now
+	m.decayLocked(r, now)
+	return r
+}
+
+func (m *Monitor) decayLocked(r *record, now time.Time) {
+	if d := now.Sub(r.updated).Seconds(); d > 0 {
+		r.Score = math.Max(0, r.Score-d*m.cfg.DecayPerSecond)
+		r.updated = now
+	}
+}
+
+func (m *Monitor) flagLocked(r *record, now time.Time, points float64, rule, detail string) {
+	r.Score += points
+	r.Flags = append(r.Flags, Flag{Time: now, Rule: rule, Detail: detail})
+	if len(r.Flags) > maxFlags {
+		r.Flags = r.Flags[len(r.Flags)-maxFlags:]
+	}
+}
+
+// verdictLocked applies the score thresholds on top of the verdict of the rule.
+// The shadow flag stays for the whole session, even when the score decays.
+func (m *Monitor) verdictLocked(r *record, v Verdict) Verdict {
+	if r.Score >= m.cfg.FlagScore {
+		r.Shadow = true
+	}
+	if r.Score >= m.cfg.KickScore {
+		r.Kicked = true
+		return Kick
+	}
+	return v
+}
+
+func meanStd(ds []time.Duration) (time.Duration, time.Duration) {
+	var sum float64
+	for _, d := range ds {
+		sum += float64(d)
+	}
+	mean := sum / float64(len(ds))
+	var sq float64
+	for _, d := range ds {
+		sq += (float64(d) - mean) * (float64(d) - mean)
+	}
+	return time.Duration(mean), time.Duration(math.Sqrt(sq / float64(len(ds))))
+}
@@ -0,0 +1,125 @@
+package anticheat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInvalidInputs(t *testing.T) {
+	m := New(DefaultConfig)
+	now := time.Now()
+	if v := m.Move("r/p-1", "A", "right", false, now); v != Accept {
+		t.Fatalf("valid move rejected: %v", v)
+	}
+	if v := m.Move("r/p-1", "A", "right", true, now); v != Reject {
+		t.Fatalf("teleport accepted: %v", v)
+	}
+	if v := m.Move("r/p-1", "A", "north", false, now); v != Reject {
+		t.Fatalf("unknown direction accepted: %v", v)
+	}
+	rep := m.Report()
+	// the report decays the score up to now: a few µs
+	if len(rep) != 1 || rep[0].Score < 2*invalidPoints-0.1 || len(rep[0].Flags) != 2 || rep[0].Flags[0].Rule != "invalid_input" {
+		t.Fatalf("unexpected report %+v", rep)
+	}
+}
+
+func TestMoveRateAndKick(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.CadenceSamples = 1 << 30 // rate rule only
+	m := New(cfg)
+	now := time.Now()
+	var v Verdict
+	// far more moves than possible in one window: rejected, then shadow-flagged, then kicked
+	i := 0
+	for ; i < 1000 && v != Kick; i++ {
+		v = m.Move("r/p-1", "A", "up", false, now.Add(time.Duration(i)*time.Millisecond))
+		if i < cfg.MaxMovesPerWindow && v != Accept {
+			t.Fatalf("move %d rejected", i)
+		}
+		if i == cfg.MaxMovesPerWindow && v != Reject {
+			t.Fatalf("move over the limit accepted")
+		}
+	}
+	if v != Kick {
+		t.Fatalf("flooding player was never kicked")
+	}
+	if !m.Shadowed("A") || m.Shadowed("B") {
+		t.Fatalf("A should be shadow-flagged, and only A")
+	}
+	// once kicked, always kicked for this session
+	if m.Move("r/p-1", "A", "up", false, now.Add(time.Hour)) != Kick {
+		t.Fatalf("kicked session accepted again")
+	}
+}
+
+func TestCadence(t *testing.T) {
+	m := New(DefaultConfig)
+	now := time.Now()
+	// a bot moving exactly every 50ms
+	for i := 0; i <= DefaultConfig.CadenceSamples; i++ {
+		m.Move("r/bot", "Bot", "up", false, now.Add(time.Duration(i)*50*time.Millisecond))
+	}
+	// a human, with a few ms of jitter
+	for i := 0; i <= DefaultConfig.CadenceSamples; i++ {
+		jitter := time.Duration(i*7%11) * time.Millisecond
+		m.Move("r/human", "Human", "up", false, now.Add(time.Duration(i)*50*time.Millisecond+jitter))
+	}
+	rep := m.Report()
+	if len(rep) != 1 || rep[0].Name != "Bot" || rep[0].Flags[0].Rule != "cadence" {
+		t.Fatalf("expected only the bot flagged, got %+v", rep)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	m := New(DefaultConfig)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		m.Move("r/p-1", "Cheater", "north", false, now) // 3 x 20 points: shadow-flagged
+	}
+	m.NotInGame("conn/1.2.3.4:5678", "", now)
+	if !m.Shadowed("Cheater") {
+		t.Fatal("cheater not shadow-flagged")
+	}
+	m.Forget("conn/1.2.3.4:5678")
+	m.Leave("r/p-1")
+	if !m.Shadowed("Cheater") || len(m.Report()) != 1 {
+		t.Fatal("the record of a player who just left should stay")
+	}
+
+	// an hour later: the shadow flag ends and the next move drops the record
+	m.mu.Lock()
+	m.records["r/p-1"].leftAt = now.Add(-keep - time.Second)
+	m.mu.Unlock()
+	if m.Shadowed("Cheater") {
+		t.Fatal("shadow flag kept after the session expired")
+	}
+	m.Move("r/p-2", "Other", "up", false, now.Add(pruneEvery))
+	m.mu.Lock()
+	_, ok := m.records["r/p-1"]
+	n := len(m.records)
+	m.mu.Unlock()
+	if ok || n != 1 {
+		t.Fatalf("expired record not pruned: %d records", n)
+	}
+}
+
+func TestSetMoveLimit(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.CadenceSamples = 1 << 30 // rate rule only
+	m := New(cfg)
+	m.SetMoveLimit(10, 1) // 10 ticks/s, 1 move per tick: 10 moves per second
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		if v := m.Move("r/p-1", "A", "up", false, now.Add(time.Duration(i)*time.Millisecond)); v != Accept {
+			t.Fatalf("move %d rejected", i)
+		}
+	}
+	if v := m.Move("r/p-1", "A", "up", false, now.Add(10*time.Millisecond)); v != Reject {
+		t.Fatal("move over the limit of the settings accepted")
+	}
+	m.SetMoveLimit(30, 3) // a reload raises max_moves: 90 moves per second
+	if v := m.Move("r/p-1", "A", "up", false, now.Add(11*time.Millisecond)); v != Accept {
+		t.Fatal("move under the new limit rejected")
+	}
+}
@@ -0,0 +1,226 @@
+// Package auth holds the player accounts and the signed tokens binding a connection to an account.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+)
+
+var (
+	ErrExists      = errors.New("account already exists")
+	ErrBadLogin    = errors.New("invalid name or password")
+	ErrBadPassword = errors.New("password must be at least 8 characters")
+)
+
+// pbkdf2 settings (OWASP recommendation for PBKDF2-HMAC-SHA256)
+const (
+	hashIterations = 600000
+	hashLen        = 32
+	saltLen        = 16
+	minPassword    = 8
+)
+
+// Account is a registered player. Only a salted hash of the password is kept.
+type Account struct {
+	Name string `json:"name"`
+	Salt []byte `json:"salt"`
+	Hash []byte `json:"hash"`
+}
+
+// Accounts is a file-backed set of accounts keyed by name, plus pre-shared API keys
+// (for bots), stored as the SHA-256 of the key.
+type Accounts struct {
+	mu       sync.Mutex
+	path     string // empty: in memory only
+	accounts map[string]*Account
+	keys     map[string]string // hex SHA-256 of the key -> account name
+}
+
+// file is the on-disk format.
+type file struct {
+	Accounts map[string]*Account `json:"accounts"`
+	APIKeys  map[string]string   `json:"api_keys"`
+}
+
+// New returns an in-memory account store.
+func New() *Accounts {
+	return &Accounts{accounts: make(map[string]*Account), keys: make(map[string]string)}
+}
+
+// Open loads the accounts from path, creating it on the first save if it does not exist.
+func Open(path string) (*Accounts, error) {
+	a := New()
+	a.path = path
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, err
+	}
+	if f.Accounts != nil {
+		a.accounts = f.Accounts
+	}
+	if f.APIKeys != nil {
+		a.keys = f.APIKeys
+	}
+	return a, nil
+}
+
+// Default is the account store used by the server, in memory unless main opens a file.
+var Default = New()
+
+// Register creates the account name with password.
+func (a *Accounts) Register(name, password string) error {
+	if len(password) < minPassword {
+		return ErrBadPassword
+	}
+	salt := make([]byte, saltLen)
+	rand.Read(salt)
+	hash, err := hashPassword(password, salt)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.existsLocked(name) {
+		return ErrExists
+	}
+	a.accounts[name] = &Account{Name: name, Salt: salt, Hash: hash}
+	return a.saveLocked()
+}
+
+// Check verifies the password of name, ignoring its case as Exists does, and returns
+// the name of the account ("Alice" for "alice").
+func (a *Accounts) Check(name, password string) (string, error) {
+	a.mu.Lock()
+	acc := a.lookupLocked(name)
+	a.mu.Unlock()
+	if acc == nil {
+		hashPassword(password, make([]byte, saltLen)) // same cost, don't reveal which names exist
+		return "", ErrBadLogin
+	}
+	hash, err := hashPassword(password, acc.Salt)
+	if err != nil || !hmac.Equal(hash, acc.Hash) {
+		return "", ErrBadLogin
+	}
+	return acc.Name, nil
+}
+
+// Exists reports whether name has an account or an API key, ignoring case ("alice" would
+// pass for "Alice").
+func (a *Accounts) Exists(name string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.existsLocked(name)
+}
+
+// lookupLocked returns the account of name, ignoring case, nil if there is none.
+func (a *Accounts) lookupLocked(name string) *Account {
+	if acc, ok := a.accounts[name]; ok {
+		return acc
+	}
+	for n, acc := range a.accounts {
+		if strings.EqualFold(n, name) {
+			return acc
+		}
+	}
+	return nil
+}
+
+func (a *Accounts) existsLocked(name string) bool {
+	if a.lookupLocked(name) != nil {
+		return true
+	}
+	// names of the API keys have no account (e.g. bots), they are taken all the same
+	for _, n := range a.keys {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewAPIKey creates a pre-shared key for name and returns it. Only its hash is stored,
+// the key cannot be recovered later. The name follows the player name rules, as at register.
+func (a *Accounts) NewAPIKey(name string) (string, error) {
+	name = game.NormalizeName(name)
+	if err := game.ValidateName(name); err != nil {
+		return "", err
+	}
+	b := make([]byte, 24)
+	rand.Read(b)
+	key := hex.EncodeToString(b)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.keys[hashKey(key)] = name
+	return key, a.saveLocked()
+}
+
+// CheckAPIKey returns the account name bound to key.
+func (a *Accounts) CheckAPIKey(key string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	name, ok := a.keys[hashKey(key)]
+	if !ok {
+		return "", ErrBadLogin
+	}
+	return name, nil
+}
+
+// hashing bounds the passwords hashed at once: a flood of logins, even for unknown names,
+// cannot take every core from the game loops, the requests beyond wait their turn.
+var hashing = make(chan struct{}, max(1, runtime.NumCPU()/2))
+
+func hashPassword(password string, salt []byte) ([]byte, error) {
+	hashing <- struct{}{}
+	defer func() { <-hashing }()
+	return pbkdf2.Key(sha256.New, password, salt, hashIterations, hashLen)
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// saveLocked writes the accounts to a temporary file then renames it, like the profile store.
+func (a *Accounts) saveLocked() error {
+	if a.path == "" {
+		return nil
+	}
+	b, err := json.Marshal(file{Accounts: a.accounts, APIKeys: a.keys})
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(a.path), ".accounts-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	// CreateTemp makes the file 0600: password hashes stay readable by the owner only
+	return os.Rename(tmp.Name(), a.path)
+}
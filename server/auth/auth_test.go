@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.json")
+	a, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Register("Alice", "short"); err != ErrBadPassword {
+		t.Fatalf("expected ErrBadPassword, got %v", err)
+	}
+	if err := a.Register("Alice", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Register("Alice", "another one"); err != ErrExists {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	key, err := a.NewAPIKey("  Bot ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.NewAPIKey("no spaces allowed!"); err == nil {
+		t.Fatal("invalid name accepted for an api key")
+	}
+	// the name of a key is taken, as an account name
+	if !a.Exists("bot") {
+		t.Fatal("api key name not reserved")
+	}
+
+	// reload from disk
+	a, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, err := a.Check("Alice", "correct horse"); err != nil || name != "Alice" {
+		t.Fatalf("valid login refused: %q, %v", name, err)
+	}
+	// any case, as the name is taken in any case
+	if name, err := a.Check("aLICE", "correct horse"); err != nil || name != "Alice" {
+		t.Fatalf("login ignoring case: got %q, %v", name, err)
+	}
+	if _, err := a.Check("Alice", "wrong password"); err == nil {
+		t.Fatalf("invalid login accepted")
+	}
+	if _, err := a.Check("Bob", "correct horse"); err == nil {
+		t.Fatalf("invalid login accepted")
+	}
+	if name, err := a.CheckAPIKey(key); err != nil || name != "Bot" {
+		t.Fatalf("api key: got %q, %v", name, err)
+	}
+	if _, err := a.CheckAPIKey("nope"); err == nil {
+		t.Fatalf("unknown api key accepted")
+	}
+}
+
+func TestHashingBounded(t *testing.T) {
+	// every slot taken: a login waits for one
+	for i := 0; i < cap(hashing); i++ {
+		hashing <- struct{}{}
+	}
+	done := make(chan struct{})
+	go func() {
+		New().Check("Nobody", "some password")
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("password hashed beyond the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+	for i := 0; i < cap(hashing); i++ {
+		<-hashing
+	}
+	<-done
+}
+
+func TestTokens(t *testing.T) {
+	s := NewSigner([]byte("secret"))
+	tok, _ := s.Issue("Alice")
+	if name, err := s.Verify(tok); err != nil || name != "Alice" {
+		t.Fatalf("verify: got %q, %v", name, err)
+	}
+	// another key, or a modified payload, must be rejected
+	if _, err := NewSigner([]byte("other")).Verify(tok); err != ErrBadToken {
+		t.Fatalf("expected ErrBadToken with another key, got %v", err)
+	}
+	forged, _ := s.Issue("Mallory")
+	p, _, _ := strings.Cut(forged, ".")
+	_, sig, _ := strings.Cut(tok, ".")
+	if _, err := s.Verify(p + "." + sig); err != ErrBadToken {
+		t.Fatalf("expected ErrBadToken for a forged token, got %v", err)
+	}
+	if _, err := s.Verify("garbage"); err != ErrBadToken {
+		t.Fatalf("expected ErrBadToken, got %v", err)
+	}
+
+	defer func(ttl time.Duration) { TokenTTL = ttl }(TokenTTL)
+	TokenTTL = -time.Second
+	old, _ := s.Issue("Alice")
+	if _, err := s.Verify(old); err != ErrTokenExpired {
+		t.Fatalf("expected ErrTokenExpired, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBadToken     = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+)
+
+// TokenTTL is the lifetime of the tokens issued by Issue.
+var TokenTTL = 24 * time.Hour
+
+// claims is the signed payload of a token.
+type claims struct {
+	Sub string `json:"sub"` // account name
+	Exp int64  `json:"exp"` // unix time
+}
+
+// Signer issues and verifies tokens "<payload>.<signature>", both base64url,
+// signed with HMAC-SHA256. Restarting with another key invalidates every token.
+type Signer struct {
+	key []byte
+}
+
+// NewSigner returns a signer using key, or a random key if it is empty.
+func NewSigner(key []byte) *Signer {
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+	return &Signer{key: key}
+}
+
+// Tokens is the signer used by the server, with a random key unless main sets a secret.
+var Tokens = NewSigner(nil)
+
+// Issue returns a token for name, valid for TokenTTL.
+func (s *Signer) Issue(name string) (string, time.Time) {
+	exp := time.Now().Add(TokenTTL)
+	payload, _ := json.Marshal(claims{Sub: name, Exp: exp.Unix()})
+	p := base64.RawURLEncoding.EncodeToString(payload)
+	return p + "." + base64.RawURLEncoding.EncodeToString(s.sign(p)), exp
+}
+
+// Verify checks the signature and expiry of token and returns the account name.
+func (s *Signer) Verify(token string) (string, error) {
+	p, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", ErrBadToken
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, s.sign(p)) {
+		return "", ErrBadToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(p)
+	if err != nil {
+		return "", ErrBadToken
+	}
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil || c.Sub == "" {
+		return "", ErrBadToken
+	}
+	if time.Now().Unix() >= c.Exp {
+		return "", ErrTokenExpired
+	}
+	return c.Sub, nil
+}
+
+func (s *Signer) sign(payload string) []byte {
+	m := hmac.New(sha256.New, s.key)
+	m.Write([]byte(payload))
+	return m.Sum(nil)
+}
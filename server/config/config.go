@@ -0,0 +1,505 @@
+// Package config gathers the settings of the server. Each setting comes, from the
+// weakest to the strongest, from its default, the config file (JSON, YAML or TOML),
+// an environment variable and a command line flag.
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix starts the environment variables: game.width is SUPERSERVEUR_GAME_WIDTH.
+const EnvPrefix = "SUPERSERVEUR_"
+
+// Config holds every setting of the server.
+type Config struct {
+	Addr  string
+	MOTD  string // message of the day, sent to the players when they join
+	Game  Game
+	Match Match
+	Map   Map
+	Net   Net
+	Auth  Auth
+	TLS   TLS
+	Files Files
+	Log   Log
+}
+
+// Game settings, of every room.
+type Game struct {
+	Width, Height int
+	Sweets        int           // per round
+	TPS           int           // ticks per second
+	MinTPS        int           // lowest rate under load, 0 to keep TPS
+	MaxMoves      int           // per player and per tick
+	Intermission  time.Duration // between two rounds
+	Endless       time.Duration // round duration of the endless mode, 0 for classic rounds
+	Respawn       time.Duration // endless mode delay before a collected sweet comes back
+	MaxPlayers    int           // per room, 0 for no limit
+	RoomIdle      time.Duration // how long an empty matched room sleeps before it is removed
+}
+
+// Match settings of the matchmaking queue (see routes/matchmaking.go).
+type Match struct {
+	Size   int     // players per matched room
+	Window float64 // rating spread accepted at first
+	Widen  float64 // added to the window per second of waiting
+}
+
+// Map settings: the layout of the board and where the sweets go, in every room.
+type Map struct {
+	Walls     []string // cells "x:y"
+	Spawns    []string // cells "x:y" where the players appear, empty for anywhere
+	Placement string   // sweet placement strategy: uniform, clustered or symmetric
+	Spacing   int      // minimum distance between two sweets, 0 for none
+	Regions   int      // sweets spread evenly over Regions x Regions areas, 0 or 1 for none
+	Clusters  int      // clustered placement only: number of clusters, 0 for 3
+}
+
+// Net settings: admission and buffers.
+type Net struct {
+	Origins         []string
+	MaxConns        int
+	MaxConnsPerIP   int
+	SendBuffer      int     // messages waiting to be written to a client
+	BroadcastBuffer int     // messages of a game waiting for its hub
+	MsgRate         float64 // messages per second per client
+	MsgBurst        float64
+}
+
+// Auth settings.
+type Auth struct {
+	Required   bool
+	Secret     string
+	AdminToken string
+	Bans       []string // names and accounts banned
+}
+
+// TLS settings: with a certificate the server only speaks HTTPS and wss://.
+type TLS struct {
+	Cert, Key string // PEM files, read again when they change
+	Redirect  string // address of a plain HTTP listener redirecting to HTTPS, empty for none
+}
+
+// Files read and written by the server, empty to keep the data in memory.
+type Files struct {
+	DB        string
+	Accounts  string
+	Blocklist string
+	Record    string
+}
+
+// Log settings.
+type Log struct {
+	Level  string
+	Format string
+	Sample int
+}
+
+// Default returns the settings used when nothing is set.
+func Default() *Config {
+	return &Config{
+		Addr: "localhost:8080",
+		Game: Game{Width: 10, Height: 10, Sweets: 20, TPS: 20, MaxMoves: 2, Intermission: 5 * time.Second,
+			Respawn: 5 * time.Second, RoomIdle: time.Minute},
+		Match: Match{Size: 4, Window: 100, Widen: 25},
+		Map:   Map{Placement: "uniform"},
+		Net:   Net{MaxConns: 1000, SendBuffer: 256, BroadcastBuffer: 10, MsgRate: 20, MsgBurst: 40},
+		Files: Files{DB: "players.json", Accounts: "accounts.json"},
+		Log:   Log{Level: "info", Format: "text", Sample: 100},
+	}
+}
+
+// setting is a key of the config file, with its flag and the field it sets.
+type setting struct {
+	key   string // section.name in the config file
+	flag  string
+	env   string // SUPERSERVEUR_ + key by default
+	usage string
+	field func(c *Config) interface{} // pointer to the field
+}
+
+// live are the settings a reload applies (see Watch), the others need a restart.
+var live = map[string]bool{
+	"motd": true, "game.sweets": true, "game.max_moves": true, "game.intermission": true,
+	"game.max_players": true, "game.room_idle": true, "net.origins": true, "net.max_conns": true,
+	"net.max_conns_ip": true, "net.msg_rate": true, "net.msg_burst": true, "auth.admin_token": true,
+	"auth.bans": true, "files.blocklist": true, "match.size": true, "match.window": true, "match.widen": true,
+}
+
+var settings = []setting{
+	{"addr", "addr", "", "http service address", func(c *Config) interface{} { return &c.Addr }},
+	{"motd", "motd", "", "message of the day, sent to the players when they join", func(c *Config) interface{} { return &c.MOTD }},
+	{"game.width", "width", "", "grid width", func(c *Config) interface{} { return &c.Game.Width }},
+	{"game.height", "height", "", "grid height", func(c *Config) interface{} { return &c.Game.Height }},
+	{"game.sweets", "sweets", "", "sweets placed at the start of a round", func(c *Config) interface{} { return &c.Game.Sweets }},
+	{"game.tps", "tps", "", "ticks per second", func(c *Config) interface{} { return &c.Game.TPS }},
+	{"game.min_tps", "min-tps", "", "lowest tick rate a room may drop to when its loop falls behind, 0 to keep the rate fixed", func(c *Config) interface{} { return &c.Game.MinTPS }},
+	{"game.max_moves", "max-moves", "", "moves a player may make per tick", func(c *Config) interface{} { return &c.Game.MaxMoves }},
+	{"game.intermission", "intermission", "", "pause between two rounds", func(c *Config) interface{} { return &c.Game.Intermission }},
+	{"game.endless", "endless", "", "endless mode round duration (e.g. 2m), 0 for classic rounds", func(c *Config) interface{} { return &c.Game.Endless }},
+	{"game.respawn", "respawn", "", "endless mode delay before a collected sweet respawns", func(c *Config) interface{} { return &c.Game.Respawn }},
+	{"game.max_players", "max-players", "", "maximum players per room, 0 for no limit", func(c *Config) interface{} { return &c.Game.MaxPlayers }},
+	{"game.room_idle", "room-idle", "", "how long an empty matched room sleeps before it is removed, 0 to remove it at once", func(c *Config) interface{} { return &c.Game.RoomIdle }},
+	{"match.size", "match-size", "", "players per room formed by the matchmaking queue", func(c *Config) interface{} { return &c.Match.Size }},
+	{"match.window", "match-window", "", "rating spread the matchmaking queue accepts at first", func(c *Config) interface{} { return &c.Match.Window }},
+	{"match.widen", "match-widen", "", "rating spread added per second of waiting in the matchmaking queue", func(c *Config) interface{} { return &c.Match.Widen }},
+	{"map.walls", "walls", "", "comma-separated wall cells x:y (e.g. 4:4,4:5)", func(c *Config) interface{} { return &c.Map.Walls }},
+	{"map.spawns", "spawns", "", "comma-separated cells x:y where the players appear, empty for anywhere", func(c *Config) interface{} { return &c.Map.Spawns }},
+	{"map.placement", "placement", "", "sweet placement: uniform, clustered (around a few centres) or symmetric (mirrored pairs)", func(c *Config) interface{} { return &c.Map.Placement }},
+	{"map.sweet_spacing", "sweet-spacing", "", "minimum distance between two sweets (in cells), 0 for none", func(c *Config) interface{} { return &c.Map.Spacing }},
+	{"map.regions", "regions", "", "spread the sweets evenly over N x N areas of the board, 0 for none", func(c *Config) interface{} { return &c.Map.Regions }},
+	{"map.clusters", "clusters", "", "clusters of the clustered placement, 0 for 3", func(c *Config) interface{} { return &c.Map.Clusters }},
+	{"net.origins", "origins", "", "comma-separated origins allowed to open a WebSocket (e.g. https://jeu.example.com,*.example.com), empty for any", func(c *Config) interface{} { return &c.Net.Origins }},
+	{"net.max_conns", "max-conns", "", "maximum WebSocket connections, 0 for no limit", func(c *Config) interface{} { return &c.Net.MaxConns }},
+	{"net.max_conns_ip", "max-conns-ip", "", "maximum WebSocket connections per IP, 0 for no limit", func(c *Config) interface{} { return &c.Net.MaxConnsPerIP }},
+	{"net.send_buffer", "send-buffer", "", "messages waiting to be written to a client before it is dropped", func(c *Config) interface{} { return &c.Net.SendBuffer }},
+	{"net.broadcast_buffer", "broadcast-buffer", "", "messages of a game waiting for the clients before they are dropped", func(c *Config) interface{} { return &c.Net.BroadcastBuffer }},
+	{"net.msg_rate", "msg-rate", "", "messages per second a client may send", func(c *Config) interface{} { return &c.Net.MsgRate }},
+	{"net.msg_burst", "msg-burst", "", "messages a client may send at once", func(c *Config) interface{} { return &c.Net.MsgBurst }},
+	{"auth.required", "auth", "", "reject WebSocket connections without a valid token", func(c *Config) interface{} { return &c.Auth.Required }},
+	{"auth.secret", "secret", "SUPERSERVEUR_SECRET", "token signing key (random if empty: tokens die with the server)", func(c *Config) interface{} { return &c.Auth.Secret }},
+	{"auth.admin_token", "admin-token", "SUPERSERVEUR_ADMIN_TOKEN", "token of the admin API, empty disables it", func(c *Config) interface{} { return &c.Auth.AdminToken }},
+	{"auth.bans", "bans", "", "comma-separated player names and accounts banned (see also the admin API)", func(c *Config) interface{} { return &c.Auth.Bans }},
+	{"tls.cert", "tls-cert", "", "TLS certificate file (PEM), reloaded when it changes; serves HTTPS and wss:// only", func(c *Config) interface{} { return &c.TLS.Cert }},
+	{"tls.key", "tls-key", "", "TLS private key file (PEM) of the certificate", func(c *Config) interface{} { return &c.TLS.Key }},
+	{"tls.redirect", "tls-redirect", "", "address of a plain HTTP listener redirecting to HTTPS (e.g. :80), empty for none", func(c *Config) interface{} { return &c.TLS.Redirect }},
+	{"files.db", "db", "", "player profiles file, empty to keep them in memory", func(c *Config) interface{} { return &c.Files.DB }},
+	{"files.accounts", "accounts", "", "player accounts file, empty to keep them in memory", func(c *Config) interface{} { return &c.Files.Accounts }},
+	{"files.blocklist", "blocklist", "", "file of words refused in player names (one per line)", func(c *Config) interface{} { return &c.Files.Blocklist }},
+	{"files.record", "record", "", "record the match to this file", func(c *Config) interface{} { return &c.Files.Record }},
+	{"log.level", "log-level", "", "log level: debug, info, warn or error", func(c *Config) interface{} { return &c.Log.Level }},
+	{"log.format", "log-format", "", "log format: text or json", func(c *Config) interface{} { return &c.Log.Format }},
+	{"log.sample", "log-sample", "", "log 1 in N moves and dropped messages per connection (debug level for moves), 0 for none", func(c *Config) interface{} { return &c.Log.Sample }},
+}
+
+func init() {
+	for i := range settings {
+		if settings[i].env == "" {
+			settings[i].env = EnvPrefix + strings.ToUpper(strings.ReplaceAll(settings[i].key, ".", "_"))
+		}
+	}
+}
+
+func lookup(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// Set parses value into the setting key (e.g. "game.width").
+func (c *Config) Set(key, value string) error {
+	s, ok := lookup(key)
+	if !ok {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	value = strings.TrimSpace(value)
+	var err error
+	switch p := s.field(c).(type) {
+	case *string:
+		*p = value
+	case *int:
+		*p, err = strconv.Atoi(value)
+	case *float64:
+		*p, err = strconv.ParseFloat(value, 64)
+	case *bool:
+		*p, err = strconv.ParseBool(value)
+	case *time.Duration:
+		*p, err = time.ParseDuration(value)
+	case *[]string:
+		*p = nil
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				*p = append(*p, v)
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%s: invalid value %q", key, value)
+	}
+	return nil
+}
+
+// get formats the setting key, the way Set reads it.
+func (c *Config) get(key string) string {
+	s, _ := lookup(key)
+	switch p := s.field(c).(type) {
+	case *string:
+		return *p
+	case *int:
+		return strconv.Itoa(*p)
+	case *float64:
+		return strconv.FormatFloat(*p, 'g', -1, 64)
+	case *bool:
+		return strconv.FormatBool(*p)
+	case *time.Duration:
+		return p.String()
+	case *[]string:
+		return strings.Join(*p, ",")
+	}
+	return ""
+}
+
+// Change is a setting whose value differs between two configs.
+type Change struct {
+	Key, Old, New string
+	Live          bool // applied by a reload, the others need a restart
+}
+
+// Changes lists the settings whose value differ from c to o. Secrets are not shown.
+func (c *Config) Changes(o *Config) []Change {
+	var list []Change
+	for _, s := range settings {
+		a, b := c.get(s.key), o.get(s.key)
+		if a == b {
+			continue
+		}
+		if s.key == "auth.secret" || s.key == "auth.admin_token" {
+			a, b = "***", "***"
+		}
+		list = append(list, Change{Key: s.key, Old: a, New: b, Live: live[s.key]})
+	}
+	return list
+}
+
+// Ticks converts a duration to a number of ticks at the configured tick rate.
+func (c *Config) Ticks(d time.Duration) int64 {
+	return int64(d * time.Duration(c.Game.TPS) / time.Second)
+}
+
+// Validate checks the settings.
+func (c *Config) Validate() error {
+	g, n := c.Game, c.Net
+	switch {
+	case c.Addr == "":
+		return fmt.Errorf("addr: empty address")
+	case g.Width < 1 || g.Height < 1 || g.Width*g.Height > 1000000:
+		return fmt.Errorf("game: invalid grid size %dx%d", g.Width, g.Height)
+	case g.Sweets < 0 || g.Sweets >= g.Width*g.Height:
+		return fmt.Errorf("game.sweets: expected 0 to %d on a %dx%d grid", g.Width*g.Height-1, g.Width, g.Height)
+	case g.TPS < 1 || g.TPS > 1000:
+		return fmt.Errorf("game.tps: expected 1 to 1000")
+	case g.MinTPS < 0 || g.MinTPS > g.TPS:
+		return fmt.Errorf("game.min_tps: expected 0 to game.tps (%d)", g.TPS)
+	case g.MaxMoves < 1:
+		return fmt.Errorf("game.max_moves: expected at least 1")
+	case g.Intermission < 0 || g.Endless < 0 || g.Respawn < 0 || g.RoomIdle < 0:
+		return fmt.Errorf("game: durations must not be negative")
+	case g.Endless > 0 && c.Ticks(g.Endless) < 1:
+		return fmt.Errorf("game.endless: shorter than a tick")
+	case c.Match.Size < 2:
+		return fmt.Errorf("match.size: expected at least 2 players")
+	case c.Match.Window < 0 || c.Match.Widen < 0:
+		return fmt.Errorf("match: window and widen must not be negative")
+	case g.MaxPlayers < 0 || n.MaxConns < 0 || n.MaxConnsPerIP < 0:
+		return fmt.Errorf("limits must not be negative")
+	case n.SendBuffer < 1 || n.BroadcastBuffer < 1:
+		return fmt.Errorf("net: buffers must hold at least 1 message")
+	case n.MsgRate <= 0 || n.MsgBurst < 1:
+		return fmt.Errorf("net: msg_rate must be positive and msg_burst at least 1")
+	case (c.TLS.Cert == "") != (c.TLS.Key == ""):
+		return fmt.Errorf("tls: cert and key go together")
+	case c.TLS.Redirect != "" && c.TLS.Cert == "":
+		return fmt.Errorf("tls.redirect: needs a certificate")
+	case c.Map.Spacing < 0 || c.Map.Regions < 0 || c.Map.Clusters < 0:
+		return fmt.Errorf("map: sweet_spacing, regions and clusters must not be negative")
+	case c.Map.Regions > min(g.Width, g.Height):
+		return fmt.Errorf("map.regions: at most %d on a %dx%d grid", min(g.Width, g.Height), g.Width, g.Height)
+	case c.Log.Sample < 0:
+		return fmt.Errorf("log.sample must not be negative")
+	}
+	switch c.Map.Placement {
+	case "uniform", "clustered", "symmetric":
+	default:
+		return fmt.Errorf("map.placement %q: expected uniform, clustered or symmetric", c.Map.Placement)
+	}
+	walls, err := c.cells("map.walls", c.Map.Walls)
+	if err != nil {
+		return err
+	}
+	if _, err := c.cells("map.spawns", c.Map.Spawns); err != nil {
+		return err
+	}
+	for _, cell := range c.Map.Spawns {
+		if x, y, _ := Cell(cell); walls[[2]int{x, y}] {
+			return fmt.Errorf("map.spawns: %s is a wall", cell)
+		}
+	}
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("log.level %q: expected debug, info, warn or error", c.Log.Level)
+	}
+	switch strings.ToLower(c.Log.Format) {
+	case "text", "json":
+	default:
+		return fmt.Errorf("log.format %q: expected text or json", c.Log.Format)
+	}
+	return nil
+}
+
+// cells checks the cells of the map setting key and returns them as a set.
+func (c *Config) cells(key string, list []string) (map[[2]int]bool, error) {
+	set := make(map[[2]int]bool, len(list))
+	for _, cell := range list {
+		x, y, err := Cell(cell)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if x >= c.Game.Width || y >= c.Game.Height {
+			return nil, fmt.Errorf("%s: %s outside the %dx%d grid", key, cell, c.Game.Width, c.Game.Height)
+		}
+		set[[2]int{x, y}] = true
+	}
+	return set, nil
+}
+
+// Cell parses a cell of the map settings, "x:y".
+func Cell(s string) (x, y int, err error) {
+	xs, ys, ok := strings.Cut(s, ":")
+	if ok {
+		x, err = strconv.Atoi(strings.TrimSpace(xs))
+	}
+	if ok && err == nil {
+		y, err = strconv.Atoi(strings.TrimSpace(ys))
+	}
+	if !ok || err != nil || x < 0 || y < 0 {
+		return 0, 0, fmt.Errorf("invalid cell %q, expected x:y", s)
+	}
+	return x, y, nil
+}
+
+// Flags are the command line flags of the settings, see Register.
+type Flags struct {
+	path   string
+	set    map[string]string // settings given on the command line, by key
+	loaded []byte            // content of the file at the last Load
+}
+
+// Register adds a flag per setting to fs, plus -config for the config file.
+// The flags keep the value given on the command line, Load applies them last.
+func Register(fs *flag.FlagSet) *Flags {
+	f := &Flags{set: make(map[string]string)}
+	fs.StringVar(&f.path, "config", os.Getenv(EnvPrefix+"CONFIG"), "config file (.json, .yaml or .toml), default $"+EnvPrefix+"CONFIG")
+	def := Default()
+	for _, s := range settings {
+		v := &flagValue{flags: f, key: s.key, def: def.get(s.key)}
+		_, v.isBool = s.field(def).(*bool)
+		if v.def == "false" || v.def == "0" || v.def == "0s" {
+			v.def = "" // no "(default 0)" in the usage
+		}
+		fs.Var(v, s.flag, s.usage+" ($"+s.env+")")
+	}
+	return f
+}
+
+// Path returns the config file given with -config.
+func (f *Flags) Path() string { return f.path }
+
+// Load reads the settings: defaults, then the config file, the environment and the flags.
+func (f *Flags) Load() (*Config, error) {
+	c, data, err := load(f.path, os.LookupEnv, f.set)
+	f.loaded = data
+	return c, err
+}
+
+// load returns the config and the content of the file it was read from.
+func load(path string, env func(string) (string, bool), flags map[string]string) (*Config, []byte, error) {
+	c := Default()
+	var data []byte
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, nil, err
+		}
+		values, err := Parse(path, data)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, k := range sortedKeys(values) {
+			if err := c.Set(k, values[k]); err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+	for _, s := range settings {
+		if v, ok := env(s.env); ok {
+			if err := c.Set(s.key, v); err != nil {
+				return nil, nil, fmt.Errorf("$%s: %w", s.env, err)
+			}
+		}
+	}
+	for _, k := range sortedKeys(flags) {
+		if err := c.Set(k, flags[k]); err != nil {
+			return nil, nil, err
+		}
+	}
+	return c, data, c.Validate()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// flagValue is the flag of a setting: Set checks the value and keeps it for Load.
+type flagValue struct {
+	flags  *Flags
+	key    string
+	def    string
+	isBool bool
+}
+
+func (v *flagValue) String() string {
+	if v == nil || v.flags == nil {
+		return ""
+	}
+	if s, ok := v.flags.set[v.key]; ok {
+		return s
+	}
+	return v.def
+}
+
+func (v *flagValue) Set(s string) error {
+	if err := Default().Set(v.key, s); err != nil {
+		return err
+	}
+	v.flags.set[v.key] = s
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool { return v.isBool }
+
+// Parse reads the content of a config file into settings by key, the format is given
+// by the extension of path.
+func Parse(path string, b []byte) (map[string]string, error) {
+	var err error
+	var values map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		values, err = parseJSON(b)
+	case ".yaml", ".yml":
+		values, err = parseYAML(b)
+	case ".toml":
+		values, err = parseTOML(b)
+	default:
+		return nil, fmt.Errorf("%s: unknown config format, expected .json, .yaml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// the same settings in the three formats
+var files = map[string]string{
+	"server.json": `{
+  "addr": ":9000",
+  "game": {"width": 20, "height": 15, "sweets": 40, "intermission": "2s"},
+  "net": {"origins": ["https://a.example", "*.b.example"], "send_buffer": 64},
+  "auth": {"required": true}
+}`,
+	"server.yaml": `# server settings
+addr: ":9000"
+game:
+  width: 20
+  height: 15
+  sweets: 40   # per round
+  intermission: 2s
+net:
+  origins:
+    - https://a.example
+    - "*.b.example"
+  send_buffer: 64
+auth:
+  required: true
+`,
+	"server.toml": `addr = ":9000"
+
+[game]
+width = 20
+height = 15
+sweets = 40 # per round
+intermission = "2s"
+
+[net]
+origins = ["https://a.example", "*.b.example"]
+send_buffer = 64
+
+[auth]
+required = true
+`,
+}
+
+func TestReadFormats(t *testing.T) {
+	want := Default()
+	want.Addr = ":9000"
+	want.Game.Width, want.Game.Height, want.Game.Sweets, want.Game.Intermission = 20, 15, 40, 2*time.Second
+	want.Net.Origins, want.Net.SendBuffer = []string{"https://a.example", "*.b.example"}, 64
+	want.Auth.Required = true
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0o600)
+		c, _, err := load(path, func(string) (string, bool) { return "", false }, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(c, want) {
+			t.Fatalf("%s: got %+v, expected %+v", name, c, want)
+		}
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	os.WriteFile(path, []byte("game:\n  width: 20\n  height: 15\n  tps: 30\n"), 0o600)
+	env := map[string]string{"SUPERSERVEUR_GAME_HEIGHT": "12", "SUPERSERVEUR_GAME_TPS": "25", "SUPERSERVEUR_ADMIN_TOKEN": "t0k"}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	f := Register(fs)
+	if err := fs.Parse([]string{"-config", path, "-tps", "40", "-auth"}); err != nil {
+		t.Fatal(err)
+	}
+	c, _, err := load(f.Path(), func(k string) (string, bool) { v, ok := env[k]; return v, ok }, f.set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// file < environment < flags
+	if c.Game.Width != 20 || c.Game.Height != 12 || c.Game.TPS != 40 || !c.Auth.Required || c.Auth.AdminToken != "t0k" {
+		t.Fatalf("unexpected config %+v", c)
+	}
+	if c.Game.Sweets != 20 || c.Addr != "localhost:8080" {
+		t.Fatalf("defaults lost: %+v", c)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	dir := t.TempDir()
+	none := func(string) (string, bool) { return "", false }
+	for content, msg := range map[string]string{
+		`{"game": {"width": "ten"}}`:                            "invalid value",
+		`{"game": {"colour": "red"}}`:                           "unknown setting",
+		`{"game": {"max_moves": 0}}`:                            "max_moves",
+		`{"game": {"sweets": 100}}`:                             "game.sweets",
+		`{"game": {"min_tps": 30}}`:                             "min_tps",
+		`{"log": {"level": "verbose"}}`:                         "log.level",
+		`{"net": {"send_buffer": 0}}`:                           "buffers",
+		`{"game": {"intermission": -1}}`:                        "invalid value", // durations need a unit
+		`{"tls": {"cert": "c.pem"}}`:                            "cert and key",
+		`{"tls": {"redirect": ":80"}}`:                          "needs a certificate",
+		`{"match": {"size": 1}}`:                                "match.size",
+		`{"match": {"widen": -5}}`:                              "widen",
+		`{"map": {"placement": "spiral"}}`:                      "map.placement",
+		`{"map": {"regions": 11}}`:                              "map.regions",
+		`{"map": {"walls": ["3-4"]}}`:                           "invalid cell",
+		`{"map": {"walls": ["2:10"]}}`:                          "outside",
+		`{"map": {"walls": ["1:1"], "spawns": ["0:0", "1:1"]}}`: "1:1 is a wall",
+		`{"game":`: "unexpected EOF",
+	} {
+		path := filepath.Join(dir, "bad.json")
+		os.WriteFile(path, []byte(content), 0o600)
+		if _, _, err := load(path, none, nil); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Fatalf("%s: expected an error about %q, got %v", content, msg, err)
+		}
+	}
+	if _, _, err := load(filepath.Join(dir, "server.ini"), none, nil); err == nil {
+		t.Fatal("missing file accepted")
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(new(strings.Builder))
+	Register(fs)
+	if err := fs.Parse([]string{"-tps", "fast"}); err == nil {
+		t.Fatal("invalid flag value accepted")
+	}
+}
+
+func TestChanges(t *testing.T) {
+	a, b := Default(), Default()
+	b.Game.Sweets = 30
+	b.Game.Width = 20
+	b.Auth.AdminToken = "secret"
+	b.Match.Size = 2
+	got := a.Changes(b)
+	want := []Change{
+		{"game.width", "10", "20", false},
+		{"game.sweets", "20", "30", true},
+		{"match.size", "4", "2", true},
+		{"auth.admin_token", "***", "***", true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, expected %+v", got, want)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	os.WriteFile(path, []byte("game:\n  sweets: 10\n"), 0o600)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	f := Register(fs)
+	fs.Parse([]string{"-config", path})
+	cur, err := f.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied := make(chan *Config, 10)
+	var refuse error // returned by apply
+	w := f.Watch(cur, 5*time.Millisecond, func(old, cfg *Config) error {
+		if refuse != nil {
+			return refuse
+		}
+		applied <- cfg
+		return nil
+	})
+
+	// a live setting and one that needs a restart
+	os.WriteFile(path, []byte("game:\n  sweets: 30\n  width: 20\n"), 0o600)
+	select {
+	case cfg := <-applied:
+		if cfg.Game.Sweets != 30 || cfg.Game.Width != 10 {
+			t.Fatalf("expected 30 sweets on the 10 wide grid still in effect, got %+v", cfg.Game)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("file change not reloaded")
+	}
+
+	os.WriteFile(path, []byte("game:\n  sweets: -1\n"), 0o600)
+	select {
+	case cfg := <-applied:
+		t.Fatalf("invalid config applied: %+v", cfg.Game)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if w.Current().Game.Sweets != 30 {
+		t.Fatalf("previous config not kept: %+v", w.Current().Game)
+	}
+
+	w.Reload() // what SIGHUP does: still invalid
+	os.WriteFile(path, []byte("game:\n  sweets: 12\n"), 0o600)
+	w.Reload()
+	if got := w.Current().Game.Sweets; got != 12 {
+		t.Fatalf("reload gave %d sweets, expected 12", got)
+	}
+	<-applied
+	w.Stop() // the rest with Reload only
+
+	// valid as written, not on the grid still in effect
+	os.WriteFile(path, []byte("game:\n  width: 20\n  sweets: 150\n"), 0o600)
+	w.Reload()
+	if got := w.Current().Game.Sweets; got != 12 || len(applied) != 0 {
+		t.Fatalf("150 sweets applied on the 10x10 grid (%d in effect)", got)
+	}
+
+	// refused by apply, e.g. a missing block-list
+	refuse = errors.New("no block-list")
+	os.WriteFile(path, []byte("game:\n  sweets: 14\n"), 0o600)
+	w.Reload()
+	if got := w.Current().Game.Sweets; got != 12 {
+		t.Fatalf("config refused by apply kept in effect: %d sweets", got)
+	}
+}
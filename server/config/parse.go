@@ -0,0 +1,183 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The config files are small and flat (sections of key/values), so the YAML and
+// TOML readers only cover that: no dependency for a few lines of settings.
+// Every reader returns the values by "section.key", lists joined with commas.
+
+// parseJSON reads {"addr": "...", "game": {"width": 10, ...}}.
+func parseJSON(b []byte) (map[string]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var root map[string]interface{}
+	if err := dec.Decode(&root); err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for k, v := range m {
+			switch v := v.(type) {
+			case map[string]interface{}:
+				walk(prefix+k+".", v)
+			case []interface{}:
+				list := make([]string, len(v))
+				for i, e := range v {
+					list[i] = fmt.Sprint(e)
+				}
+				values[prefix+k] = strings.Join(list, ",")
+			case nil:
+				values[prefix+k] = ""
+			default:
+				values[prefix+k] = fmt.Sprint(v)
+			}
+		}
+	}
+	walk("", root)
+	return values, nil
+}
+
+// parseYAML reads "key: value" lines, nested by indentation, with "- item" or [a, b] lists.
+func parseYAML(b []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	type level struct {
+		indent int
+		prefix string
+	}
+	stack := []level{{-1, ""}}
+	list := "" // key of the list being read
+	for n, line := range strings.Split(string(b), "\n") {
+		line = stripComment(line)
+		if strings.TrimSpace(line) == "" || strings.TrimSpace(line) == "---" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "- ") || line == "-" {
+			if list == "" {
+				return nil, fmt.Errorf("line %d: list item outside of a list", n+1)
+			}
+			v, err := scalar(strings.TrimSpace(strings.TrimPrefix(line, "-")))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			if values[list] != "" {
+				v = values[list] + "," + v
+			}
+			values[list] = v
+			continue
+		}
+		k, v, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("line %d: expected key: value", n+1)
+		}
+		if _, items := values[list]; list != "" && !items && indent <= stack[len(stack)-1].indent {
+			values[list] = "" // "key:" alone is an empty value
+		}
+		list = ""
+		for indent <= stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+		key := stack[len(stack)-1].prefix + strings.TrimSpace(k)
+		if v = strings.TrimSpace(v); v == "" {
+			// a section, or a list on the next lines
+			stack = append(stack, level{indent, key + "."})
+			list = key
+			continue
+		}
+		s, err := scalar(v)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		values[key] = s
+	}
+	if _, items := values[list]; list != "" && !items {
+		values[list] = ""
+	}
+	return values, nil
+}
+
+// parseTOML reads "[section]" headers and "key = value" lines.
+func parseTOML(b []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	prefix := ""
+	for n, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(stripComment(line))
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "["):
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: expected [section]", n+1)
+			}
+			prefix = strings.TrimSpace(line[1:len(line)-1]) + "."
+		default:
+			k, v, ok := strings.Cut(line, "=")
+			if !ok || strings.TrimSpace(k) == "" {
+				return nil, fmt.Errorf("line %d: expected key = value", n+1)
+			}
+			s, err := scalar(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			values[prefix+strings.TrimSpace(k)] = s
+		}
+	}
+	return values, nil
+}
+
+// scalar unquotes a value, or joins the items of a [a, "b"] list with commas.
+func scalar(v string) (string, error) {
+	if strings.HasPrefix(v, "[") {
+		if !strings.HasSuffix(v, "]") {
+			return "", fmt.Errorf("unterminated list %s", v)
+		}
+		var items []string
+		for _, e := range strings.Split(v[1:len(v)-1], ",") {
+			if e = strings.TrimSpace(e); e == "" {
+				continue
+			}
+			s, err := scalar(e)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	}
+	if len(v) >= 2 && v[0] == '\'' && v[len(v)-1] == '\'' {
+		return v[1 : len(v)-1], nil
+	}
+	if strings.HasPrefix(v, `"`) {
+		s, err := strconv.Unquote(v)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", v)
+		}
+		return s, nil
+	}
+	return v, nil
+}
+
+// stripComment removes a # comment, unless the # is in a quoted string.
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Watcher reloads the config when its file changes or the process gets a SIGHUP.
+type Watcher struct {
+	flags     *Flags
+	apply     func(old, cfg *Config) error
+	mu        sync.Mutex
+	cur       *Config
+	data      []byte  // content of the file at the last reload
+	pending   *[]byte // new content, seen since pendingAt
+	pendingAt time.Time
+	interval  time.Duration
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// Watch checks the config file every interval (and reloads at each SIGHUP, even without
+// a file) until Stop. The live settings of a new config are passed to apply with the
+// current config, the other changes are logged and wait for a restart. An invalid config,
+// or one apply returns an error for, is logged and ignored: cur stays in effect. The environment and the flags still
+// override the file, as at startup.
+func (f *Flags) Watch(cur *Config, interval time.Duration, apply func(old, cfg *Config) error) *Watcher {
+	w := &Watcher{flags: f, apply: apply, cur: cur, interval: interval, stop: make(chan struct{}), done: make(chan struct{})}
+	w.data = f.loaded // a change since Load is seen at the first check
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer close(w.done)
+		defer signal.Stop(hup)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-hup:
+				w.Reload()
+			case <-ticker.C:
+				if w.changed() {
+					w.Reload()
+				}
+			}
+		}
+	}()
+	return w
+}
+
+// changed reports whether the content of the file changed since the last reload.
+func (w *Watcher) changed() bool {
+	if w.flags.path == "" {
+		return false
+	}
+	data, err := os.ReadFile(w.flags.path)
+	if err != nil || len(bytes.TrimSpace(data)) == 0 {
+		return false // e.g. an editor replacing the file, the next check sees it
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if bytes.Equal(data, w.data) {
+		w.pending = nil
+		return false
+	}
+	// wait for the same content during a whole interval, not to read a file being written
+	if w.pending == nil || !bytes.Equal(data, *w.pending) {
+		w.pending, w.pendingAt = &data, time.Now()
+		return false
+	}
+	if time.Since(w.pendingAt) < w.interval {
+		return false
+	}
+	w.data, w.pending = data, nil
+	return true
+}
+
+// Reload reads the config again and applies it if it is valid.
+func (w *Watcher) Reload() {
+	cfg, _, err := load(w.flags.path, os.LookupEnv, w.flags.set)
+	if err != nil {
+		slog.Error("config reload rejected, previous config kept", "err", err)
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	changes := w.cur.Changes(cfg)
+	for _, c := range changes {
+		if !c.Live {
+			cfg.Set(c.Key, w.cur.get(c.Key)) // still in effect, warned again at the next reload
+		}
+	}
+	// the live settings must hold with the others as they are, e.g. sweets on the current grid
+	err = cfg.Validate()
+	if err == nil {
+		err = w.apply(w.cur, cfg)
+	}
+	if err != nil {
+		slog.Error("config reload rejected, previous config kept", "err", err)
+		return
+	}
+	if len(changes) == 0 {
+		slog.Info("config reloaded, nothing changed") // applied anyway: files like the block-list may have
+	}
+	for _, c := range changes {
+		if c.Live {
+			slog.Info("config changed", "key", c.Key, "old", c.Old, "new", c.New)
+		} else {
+			slog.Warn("config change needs a restart, ignored", "key", c.Key, "old", c.Old, "new", c.New)
+		}
+	}
+	w.cur = cfg
+}
+
+// Current returns the config in effect.
+func (w *Watcher) Current() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.cur
+}
+
+// Stop ends the watch.
+func (w *Watcher) Stop() {
+	close(w.stop)
+	<-w.done
+}
@@ -0,0 +1,85 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Engine operations for the admin API (see routes/admin.go). Unlike the test helpers
+// (SetSweet, ClearSweets) they check their arguments and keep the board consistent,
+// and they are recorded so that a match log still replays.
+
+var (
+	ErrOutOfBoard = errors.New("cell outside the board")
+	ErrCellTaken  = errors.New("cell is a wall or already taken")
+)
+
+// Players returns a copy of the players, sorted by ID.
+func (g *Game) Players() []Player {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	list := make([]Player, 0, len(g.players))
+	for _, p := range g.players {
+		list = append(list, *p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// SpawnSweets adds n sweets on free cells, following the placement spacing when the
+// board allows it. It returns the number placed, fewer if the board is full.
+func (g *Game) SpawnSweets(n int) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.record(RecordEntry{Kind: "spawn_sweets", Count: n})
+	placed := 0
+	for ; placed < n; placed++ {
+		c, ok := g.respawnCellLocked()
+		if !ok {
+			break
+		}
+		g.placeSweetLocked(&Sweet{ID: g.newSweetIDLocked(), X: c.X, Y: c.Y})
+	}
+	return placed
+}
+
+// PlaceSweet adds a sweet on the free cell x,y and returns its ID.
+func (g *Game) PlaceSweet(x, y int) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.grid.cell(x, y) < 0 {
+		return "", ErrOutOfBoard
+	}
+	if !g.canPlaceSweetLocked(Pos{x, y}, 0) {
+		return "", ErrCellTaken
+	}
+	id := g.newSweetIDLocked()
+	g.placeSweetLocked(&Sweet{ID: id, X: x, Y: y})
+	g.record(RecordEntry{Kind: "set_sweet", ID: id, X: x, Y: y})
+	return id, nil
+}
+
+// newSweetIDLocked returns an unused sweet ID for sweets added during a round
+// (the ones placed at the start are s1..sn), not on the board nor waiting to respawn.
+// Caller must hold g.mu.
+func (g *Game) newSweetIDLocked() string {
+	used := make(map[string]bool, len(g.respawns))
+	for _, r := range g.respawns {
+		used[r.Sweet] = true
+	}
+	for k := 1; ; k++ {
+		id := fmt.Sprintf("a%d", k)
+		if _, ok := g.sweets[id]; !ok && !used[id] {
+			return id
+		}
+	}
+}
+
+// EndRound ends the current round at the next tick, as if the board was empty:
+// game_over with the current scores, then a new round after the usual delay.
+func (g *Game) EndRound() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.endRound = true
+}
@@ -0,0 +1,67 @@
+package game
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAdminOperations(t *testing.T) {
+	var log bytes.Buffer
+	g := NewGame(4, 4, 0)
+	if err := g.StartRecording(&log); err != nil {
+		t.Fatal(err)
+	}
+	p := g.AddPlayer("A")
+	g.SetPlayerPosition(p.ID, 0, 0)
+
+	if _, err := g.PlaceSweet(4, 0); err != ErrOutOfBoard {
+		t.Fatalf("expected ErrOutOfBoard, got %v", err)
+	}
+	if _, err := g.PlaceSweet(0, 0); err != ErrCellTaken {
+		t.Fatalf("expected ErrCellTaken on the player, got %v", err)
+	}
+	id, err := g.PlaceSweet(1, 0)
+	if err != nil || id != "a1" {
+		t.Fatalf("place sweet: %q %v", id, err)
+	}
+	// 16 cells, 1 player, 1 sweet: 14 left
+	if n := g.SpawnSweets(20); n != 14 || g.SweetsCount() != 15 {
+		t.Fatalf("spawned %d, %d sweets on the board", n, g.SweetsCount())
+	}
+
+	// paused: no tick, inputs dropped
+	g.Pause()
+	g.PushCommand(Command{PlayerID: p.ID, Type: "move", Dir: "right"})
+	if g.step() || g.Tick() != 0 {
+		t.Fatal("a paused game ticked")
+	}
+	g.Resume()
+	g.step()
+	if got := g.GetPlayer(p.ID); got.X != 0 || g.Tick() != 1 {
+		t.Fatalf("input sent while paused was applied: %+v", got)
+	}
+
+	g.EndRound()
+	if !g.step() {
+		t.Fatal("EndRound did not end the round")
+	}
+	g.gameOver()
+	g.Restart()
+	g.SpawnSweets(2)
+	if g.step() {
+		t.Fatal("the forced end leaked into the next round")
+	}
+	g.StopRecording()
+
+	// the added sweets replay identically
+	rp, err := LoadReplay(bytes.NewReader(log.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rp.Run(nil); err != nil {
+		t.Fatalf("replay diverged: %v", err)
+	}
+	if rp.Game.SweetsCount() != g.SweetsCount() {
+		t.Fatalf("replayed %d sweets, expected %d", rp.Game.SweetsCount(), g.SweetsCount())
+	}
+}
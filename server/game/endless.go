@@ -0,0 +1,140 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// EndlessMode makes collected sweets respawn after a delay at a new valid position;
+// the round then ends on a timer instead of when the board is empty.
+// Durations are in ticks so that a recorded match replays identically.
+type EndlessMode struct {
+	RespawnDelay  int64 `json:"respawn_delay"`  // ticks before a collected sweet comes back
+	RoundDuration int64 `json:"round_duration"` // length of a round in ticks
+}
+
+// Validate checks the endless mode settings.
+func (m EndlessMode) Validate() error {
+	if m.RespawnDelay < 0 || m.RoundDuration <= 0 {
+		return fmt.Errorf("endless mode needs a positive round duration and respawn delay")
+	}
+	return nil
+}
+
+// Ticks converts a duration to a number of ticks at the game's tick rate (set by Start).
+func (g *Game) Ticks(d time.Duration) int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return int64(d * time.Duration(g.tps) / time.Second)
+}
+
+// Endless returns a copy of the endless mode settings, nil in classic mode.
+func (g *Game) Endless() *EndlessMode {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.endless == nil {
+		return nil
+	}
+	m := *g.endless
+	return &m
+}
+
+// respawn is a sweet waiting in the spawn queue.
+type respawn struct {
+	Tick  int64  `json:"tick"`  // tick at which the sweet comes back
+	Sweet string `json:"sweet"` // sweet ID
+}
+
+// SetEndless switches to endless mode (nil for the classic mode, where the round ends
+// when every sweet is collected). The round timer starts now.
+func (g *Game) SetEndless(m *EndlessMode) error {
+	if m != nil {
+		if err := m.Validate(); err != nil {
+			return err
+		}
+		cp := *m
+		m = &cp
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.endless = m
+	g.roundStart = g.tick
+	g.respawns = nil
+	g.record(RecordEntry{Kind: "endless", Endless: m})
+	return nil
+}
+
+// scheduleRespawnLocked queues a collected sweet. Caller must hold g.mu.
+func (g *Game) scheduleRespawnLocked(id string) {
+	if g.endless == nil {
+		return
+	}
+	r := respawn{Tick: g.tick + g.endless.RespawnDelay, Sweet: id}
+	// keep the queue sorted by tick (FIFO for a same tick)
+	i := sort.Search(len(g.respawns), func(i int) bool { return g.respawns[i].Tick > r.Tick })
+	g.respawns = append(g.respawns, respawn{})
+	copy(g.respawns[i+1:], g.respawns[i:])
+	g.respawns[i] = r
+}
+
+// respawnLocked places the sweets due this tick and emits a "spawned" event for each.
+// A sweet with no valid cell left stays in the queue and is tried again next tick.
+// Caller must hold g.mu.
+func (g *Game) respawnLocked() {
+	for len(g.respawns) > 0 && g.respawns[0].Tick <= g.tick {
+		r := g.respawns[0]
+		c, ok := g.respawnCellLocked()
+		if !ok {
+			return // board full, try again next tick
+		}
+		g.respawns = g.respawns[1:]
+		g.placeSweetLocked(&Sweet{ID: r.Sweet, X: c.X, Y: c.Y})
+		evt := map[string]interface{}{"type": "event", "event": "spawned", "sweet": r.Sweet, "x": c.X, "y": c.Y, "tick": g.tick}
+		if b, err := json.Marshal(evt); err == nil {
+			select {
+			case g.EventBroadcast <- b:
+			default:
+				broadcastsDropped.With("event").Inc()
+			}
+		}
+	}
+}
+
+// respawnCellLocked picks a valid cell for a respawning sweet following the placement spacing,
+// relaxed if the board is too crowded. Caller must hold g.mu.
+func (g *Game) respawnCellLocked() (Pos, bool) {
+	for spacing := g.placement.MinSpacing; spacing >= 0; spacing-- {
+		for a := 0; a < placementAttempts; a++ {
+			c := Pos{g.rand.Intn(g.W), g.rand.Intn(g.H)}
+			if g.canPlaceSweetLocked(c, spacing) {
+				return c, true
+			}
+		}
+		if c, ok := g.scanSweetCellLocked(spacing); ok {
+			return c, true
+		}
+	}
+	return Pos{}, false
+}
+
+// roundOverLocked reports whether the current round is finished. Caller must hold g.mu.
+func (g *Game) roundOverLocked() bool {
+	if g.endless != nil {
+		return g.tick-g.roundStart >= g.endless.RoundDuration
+	}
+	return len(g.sweets) == 0
+}
+
+// roundEndsInLocked returns the ticks left in an endless round, 0 in classic mode.
+func (g *Game) roundEndsInLocked() int64 {
+	if g.endless == nil {
+		return 0
+	}
+	left := g.roundStart + g.endless.RoundDuration - g.tick
+	if left < 0 {
+		return 0
+	}
+	return left
+}
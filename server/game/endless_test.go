@@ -0,0 +1,107 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// nextEvent returns the next event of the given kind, nil if none is queued.
+func nextEvent(g *Game, kind string) map[string]interface{} {
+	for {
+		select {
+		case b := <-g.EventBroadcast:
+			var m map[string]interface{}
+			if json.Unmarshal(b, &m) == nil && m["event"] == kind {
+				return m
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+func TestEndlessRespawn(t *testing.T) {
+	g := NewGame(5, 5, 0)
+	if err := g.SetEndless(&EndlessMode{RespawnDelay: 3, RoundDuration: 100}); err != nil {
+		t.Fatal(err)
+	}
+	p := g.AddPlayer("A")
+	g.SetPlayerPosition(p.ID, 0, 0)
+	g.SetSweet("s1", 1, 0)
+	g.PushCommand(Command{PlayerID: p.ID, Type: "move", Dir: "right"})
+	if g.step() {
+		t.Fatalf("endless round must not end on an empty board")
+	}
+	if g.SweetsCount() != 0 {
+		t.Fatalf("expected sweet collected")
+	}
+	g.step()
+	g.step()
+	if g.SweetsCount() != 0 || nextEvent(g, "spawned") != nil {
+		t.Fatalf("sweet respawned too early")
+	}
+	g.step() // tick 4 = collected at 1 + delay 3
+	if g.SweetsCount() != 1 {
+		t.Fatalf("expected sweet to respawn")
+	}
+	evt := nextEvent(g, "spawned")
+	if evt == nil || evt["sweet"] != "s1" {
+		t.Fatalf("expected spawned event for s1, got %v", evt)
+	}
+	x, y := int(evt["x"].(float64)), int(evt["y"].(float64))
+	if x == 1 && y == 0 {
+		t.Fatalf("sweet respawned under the player")
+	}
+	checkGrid(t, g)
+}
+
+func TestEndlessRoundIsTimeBoxed(t *testing.T) {
+	g := NewGame(5, 5, 3)
+	g.SetEndless(&EndlessMode{RespawnDelay: 1, RoundDuration: 5})
+	for i := 1; i < 5; i++ {
+		if g.step() {
+			t.Fatalf("round ended early at tick %d", i)
+		}
+	}
+	if !g.step() {
+		t.Fatalf("round should end after 5 ticks")
+	}
+	g.Restart()
+	if g.step() {
+		t.Fatalf("timer should restart with the round")
+	}
+	if err := g.SetEndless(&EndlessMode{RoundDuration: 0}); err == nil {
+		t.Fatalf("expected error for a zero round duration")
+	}
+}
+
+func TestEndlessReplay(t *testing.T) {
+	var log bytes.Buffer
+	g := NewGame(6, 6, 0)
+	g.StartRecording(&log)
+	g.SetEndless(&EndlessMode{RespawnDelay: 2, RoundDuration: 12})
+	p := g.AddPlayer("A")
+	g.SetPlayerPosition(p.ID, 0, 0)
+	g.SetSweet("s1", 1, 0)
+	g.SetSweet("s2", 2, 0)
+	for i := 0; i < 12; i++ {
+		g.PushCommand(Command{PlayerID: p.ID, Type: "move", Dir: []string{"right", "down", "left", "up"}[i%4]})
+		if g.step() {
+			g.gameOver()
+			g.Restart()
+		}
+	}
+	g.StopRecording()
+
+	rp, err := LoadReplay(&log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rp.Run(nil); err != nil {
+		t.Fatalf("replay diverged: %v", err)
+	}
+	if rp.Rounds != 1 {
+		t.Fatalf("expected 1 round, got %d", rp.Rounds)
+	}
+}
@@ -38,4 +38,28 @@ func TestCollectedEventEmitted(t *testing.T) {
 	default:
 		t.Fatalf("no collected event emitted")
 	}
-}
\ No newline at end of file
+}
+func TestGameOverScores(t *testing.T) {
+	g := NewGame(3, 3, 0)
+	a := g.AddPlayer("A")
+	g.SetPlayerPosition(a.ID, 0, 0)
+	b := g.AddPlayer("B")
+	g.SetPlayerPosition(b.ID, 2, 2)
+	g.SetSweet("s1", 1, 0)
+	g.PushCommand(Command{PlayerID: a.ID, Type: "move", Dir: "right"})
+	g.applyCommands()
+	<-g.EventBroadcast // collected
+	g.gameOver()
+	var m struct {
+		Type   string  `json:"type"`
+		Scores []Score `json:"scores"`
+	}
+	if err := json.Unmarshal(<-g.EventBroadcast, &m); err != nil {
+		t.Fatalf("invalid game_over json: %v", err)
+	}
+	// the same scores, in the same order, as the recording and the scores API
+	want := []Score{{ID: a.ID, Name: "A", Score: 1}, {ID: b.ID, Name: "B", Score: 0}}
+	if m.Type != "game_over" || len(m.Scores) != 2 || m.Scores[0] != want[0] || m.Scores[1] != want[1] {
+		t.Fatalf("unexpected game_over %+v", m)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -26,11 +28,11 @@ type Sweet struct {
 
 // Command from player
 type Command struct {
-	PlayerID string
-	Type     string // "move"
-	Dir      string // "up","down","left","right"
-	X        int
-	Y        int
+	PlayerID string `json:"player"`
+	Type     string `json:"type"` // "move"
+	Dir      string `json:"dir,omitempty"` // "up","down","left","right"
+	X        int    `json:"x,omitempty"`
+	Y        int    `json:"y,omitempty"`
 }
 
 // StateMessage is what the server broadcasts each tick.
@@ -39,6 +41,8 @@ type StateMessage struct {
 	Tick    int64     `json:"tick"`
 	Players []*Player `json:"players"`
 	Sweets  []*Sweet  `json:"sweets"`
+	RoundEndsIn int64 `json:"round_ends_in,omitempty"` // endless mode: ticks left in the round
+	Phase   string    `json:"phase"` // "playing" or "paused"
 }
 
 // Game contains the game state and control channels.
@@ -48,16 +52,44 @@ type Game struct {
 	// state
 	players map[string]*Player // key: player ID, value: pointer to Player
 	sweets  map[string]*Sweet // key: sweet ID, value: pointer to Sweet
-	// control
-	commands chan Command // incoming commands from players in parallel
+	grid    *grid // occupancy index of players and sweets per cell (see grid.go)
+	// spawn
+	spawnPoints []Pos // map-defined spawn points, empty for anywhere (see spawn.go)
+	nextID      int   // last player number given
+	// sweets
+	placement SweetPlacement // how sweets are placed at the start of a round (see placement.go)
+	rules     Rules          // sweets per round, move limit, intermission (see rules.go)
+	nextRules *Rules         // rules of the next round, nil to keep them
+	// endless mode, nil for classic rounds (see endless.go)
+	endless    *EndlessMode
+	roundStart int64     // tick the current round started at
+	respawns   []respawn // collected sweets waiting to come back, sorted by tick
+	// pause (see pause.go) and admin controls (see admin.go)
+	paused      bool
+	pauseReason string // PauseAdmin or PauseEmpty
+	pausedSteps int    // loop steps skipped since the pause, the state is sent once per second
+	autoPause   bool   // pause when the last player leaves
+	endRound    bool   // the round ends at the next tick
+	// control: incoming commands, one queue per player so that a spammer only fills his own
+	qmu      sync.Mutex // protects queues, separate from mu so that PushCommand never waits for a tick
+	queues   map[string][]Command
 	// broadcast state bytes
 	StateBroadcast chan []byte // chanel for broadcasting state, it's the output
 	// broadcast event bytes (e.g., collected)
 	EventBroadcast chan []byte // ponctual events like game over, sweet collected, player joined, etc.
 	// tick counter
 	tick int64 // if client receive packet in the wrong order, it will know how to handle it
+	tps  int   // ticks per second, set by Start
+	// loop control, nil when the loop is not running
+	stop chan struct{} // closed to end the loop
+	done chan struct{} // closed when the loop has returned
+	loop loopStats     // tick times, overruns and adaptive rate (see health.go)
 	// random
 	rand *rand.Rand // for random positions
+	// match log, nil when not recording (see record.go)
+	recorder *recorder
+	// called with the final scores at the end of each round (e.g. to update player profiles)
+	onRoundEnd func(scores []Score)
 }
 
 // NewGame creates a new game and initializes sweets.
@@ -67,155 +99,286 @@ func NewGame(w, h, nSweets int) *Game {
 		H:              h,
 		players:        make(map[string]*Player),
 		sweets:         make(map[string]*Sweet),
-		commands:       make(chan Command, 1024), // buffered channel for commands, to avoid blocking, it's like a big queue
-		StateBroadcast: make(chan []byte, 10), // buffered channel for state broadcasts, like a small queue because state is frequent
-		EventBroadcast: make(chan []byte, 10), // buffered channel for event broadcasts
+		queues:         make(map[string][]Command),
+		StateBroadcast: make(chan []byte, BroadcastBuffer), // buffered channel for state broadcasts, like a small queue because state is frequent
+		EventBroadcast: make(chan []byte, BroadcastBuffer), // buffered channel for event broadcasts
 		rand:           rand.New(rand.NewSource(time.Now().UnixNano())), // initialize random source
+		grid:           newGrid(w, h),
+		rules:          DefaultRules,
 	}
-	for i := 0; i < nSweets; i++ {
-		x := g.rand.Intn(w) 
-		y := g.rand.Intn(h)
-		id := fmt.Sprintf("s%d", i+1) // give an unique id
-		g.sweets[id] = &Sweet{ID: id, X: x, Y: y} // place sweet at random position
-	}
+	g.placeSweetsLocked(nSweets) // place sweets at random free positions (see placement.go)
 	return g // return pointer to game, adress in memory of the game struct
 }
 
 // Start the game loop at ticksPerSec.
 func (g *Game) Start(ticksPerSec int) {
+	stop, done := make(chan struct{}), make(chan struct{})
+	g.mu.Lock()
+	g.tps = ticksPerSec
+	g.stop, g.done = stop, done
+	g.mu.Unlock()
 	// goroutine for game loop, thread that runs concurrently
 	// the main program listen http connexion (new players), without this goroutine the game state would not update
+	g.loop.start(ticksPerSec)
 	go func() {
-		ticker := time.NewTicker(time.Second / time.Duration(ticksPerSec)) // ticker to trigger ticks at regular intervals
+		defer close(done)
+		defer g.loop.stop()
+		period := time.Second / time.Duration(ticksPerSec)
+		ticker := time.NewTicker(period) // ticker to trigger ticks at regular intervals
 		defer ticker.Stop() // clean up ticker when goroutine ends
+		last := time.Now()
 		// main game loop, runs at each tick
 		// Ensure that game runs at constant speed regardless of processing time
-		for range ticker.C {
-			g.tick++ // increment tick counter
-			g.applyCommands() // process all queued commands (Input)
-			g.broadcastState() // broadcast current state to all clients (Output)
-
+		for {
+			var now time.Time
+			select {
+			case <-stop:
+				return
+			case now = <-ticker.C:
+			}
+			// the ticker silently drops the ticks it could not deliver while we were busy
+			skipped := int((now.Sub(last) - period/2) / period)
+			last = now
+			start := time.Now()
+			over := g.step()
+			if tps := g.loop.observe(time.Since(start), skipped, period); tps > 0 {
+				period = time.Second / time.Duration(tps)
+				ticker.Reset(period)
+			}
 			// Manage end of game, check at each tick if party is over
-			if g.SweetsCount() == 0 {
-				// Recover scores 
-				g.mu.Lock() // Lock to read player scores safely (no problem if a player disconnects at this moment)
-				players := make([]map[string]interface{}, 0, len(g.players)) // prepare scores slice
-				for _, p := range g.players {
-					players = append(players, map[string]interface{}{
-						"id":    p.ID,
-						"name":  p.Name,
-						"score": p.Score,
-					})
-				}
-				g.mu.Unlock()
-
-				// Create message JSON for game over
-				msg := map[string]interface{}{
-					"type":   "game_over",
-					"scores": players,
-				}
-				b, _ := json.Marshal(msg) // serialize to JSON
-
-				// Broadcast game over message
+			if over {
+				g.gameOver()
+				g.loop.betweenRounds(true)
+				// Restart game after a delay
 				select {
-				case g.EventBroadcast <- b:
-				default: // drop if network is saturated or nobody is listening
+				case <-time.After(g.Rules().Intermission):
+				case <-stop:
+					return
 				}
-
-				// Restart game after a delay
-				time.Sleep(5 * time.Second)
 				g.Restart()
+				g.loop.betweenRounds(false)
+				last = time.Now()
 			}
 		}
 	}()
 }
 
+// Running reports whether the game loop is running.
+func (g *Game) Running() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.stop != nil
+}
+
+// Stop ends the game loop started by Start and waits for it to return.
+// The state is kept, the game can be started again.
+func (g *Game) Stop() {
+	g.mu.Lock()
+	stop, done := g.stop, g.done
+	g.stop, g.done = nil, nil
+	g.mu.Unlock()
+	if stop == nil {
+		return // not running
+	}
+	close(stop)
+	<-done
+}
+
+// step runs a single tick: process queued commands (Input) then broadcast the state (Output).
+// It returns true when the round is over (no sweets left, or time is up in endless mode).
+func (g *Game) step() bool {
+	start := time.Now()
+	defer func() { tickDuration.Observe(time.Since(start).Seconds()) }()
+	cmds := g.drainCommands()
+	// the whole tick runs under the lock: joins and leaves happen strictly between two ticks
+	g.mu.Lock()
+	if g.paused {
+		// the tick counter is frozen, inputs sent meanwhile are lost
+		g.pausedSteps++
+		beat := (g.pausedSteps-1)%max(g.tps, 1) == 0
+		g.mu.Unlock()
+		commandsDropped.With("paused").Add(uint64(len(cmds)))
+		if beat {
+			g.broadcastState() // late joiners see the board and the phase
+		}
+		return false
+	}
+	g.tick++ // increment tick counter
+	g.applyCommandsLocked(cmds)
+	g.respawnLocked() // endless mode: sweets due this tick come back (see endless.go)
+	over := g.roundOverLocked() || g.endRound
+	g.endRound = false
+	g.mu.Unlock()
+	g.broadcastState()
+	return over
+}
+
+// gameOver broadcasts the final scores of the round.
+func (g *Game) gameOver() {
+	// Recover scores
+	g.mu.Lock() // Lock to read player scores safely (no problem if a player disconnects at this moment)
+	scores := g.scoresLocked() // same order as the recording and the scores API
+	g.record(RecordEntry{Kind: "game_over", Scores: scores})
+	onRoundEnd := g.onRoundEnd
+	g.mu.Unlock()
+
+	if onRoundEnd != nil {
+		onRoundEnd(scores)
+	}
+
+	// Create message JSON for game over
+	msg := map[string]interface{}{
+		"type":   "game_over",
+		"scores": scores,
+	}
+	b, _ := json.Marshal(msg) // serialize to JSON
+
+	// Broadcast game over message
+	select {
+	case g.EventBroadcast <- b:
+	default: // drop if network is saturated or nobody is listening
+		broadcastsDropped.With("event").Inc()
+	}
+}
+
 // applyCommands processes queued commands deterministically.
 // Authorize or not the moves based on collisions and limits speed.
+// Conflicts between players are settled by a rotating priority (see priorityLocked).
 func (g *Game) applyCommands() {
-	// collect commands
+	cmds := g.drainCommands()
+	// process commands, nobody else can modify game state during this
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.applyCommandsLocked(cmds)
+}
+
+// drainCommands collects every queued command, player by player (sorted by ID, each
+// player keeps his own arrival order), and empties the queues.
+func (g *Game) drainCommands() []Command {
+	g.qmu.Lock()
+	queues := g.queues
+	g.queues = make(map[string][]Command, len(queues))
+	g.qmu.Unlock()
+	ids := make([]string, 0, len(queues))
+	for id := range queues {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
 	cmds := make([]Command, 0)
-	for {
-		select {
-		case c := <-g.commands: // while there are commands in the channel, store them in temporary cmds slice
-			cmds = append(cmds, c) // list to treat all commands at once, it's for a tick
-		default:
-			goto PROCESS // exit loop when no more commands
-		}
+	for _, id := range ids {
+		cmds = append(cmds, queues[id]...) // list to treat all commands at once, it's for a tick
 	}
-PROCESS:
+	return cmds
+}
+
+// applyCommandsLocked applies the commands of one tick. Caller must hold g.mu.
+func (g *Game) applyCommandsLocked(cmds []Command) {
 	if len(cmds) == 0 {
 		return
 	}
+	g.record(RecordEntry{Kind: "tick", Commands: cmds})
 
-	// process commands, nobody else can modify game state during this
-	g.mu.Lock()
-	defer g.mu.Unlock() 
-
-	// Limit speed: max 2 moves per tick
-	movesCount := make(map[string]int)
-	const MaxMovesPerTick = 2
+	// Limit speed: max moves per tick (2 by default, see rules.go)
+	MaxMovesPerTick := g.rules.MaxMoves
 
-	// Process commands in order
+	// Split commands per player, each player keeps his own arrival order
+	queues := make(map[string][]Command)
 	for _, c := range cmds {
-		// Ignore if exceeded move limit
-		if movesCount[c.PlayerID] >= MaxMovesPerTick {
-			continue
-		}
-
-		p, ok := g.players[c.PlayerID]
-		if !ok {
-			continue
+		if _, ok := g.players[c.PlayerID]; ok {
+			queues[c.PlayerID] = append(queues[c.PlayerID], c)
+		} else {
+			commandsDropped.With("unknown_player").Inc()
 		}
+	}
 
-		// Compute new position
-		nx, ny := p.X, p.Y
-		switch c.Type {
-		case "move":
-			switch c.Dir {
-			case "up":
-				ny = max(0, p.Y-1)
-			case "down":
-				ny = min(g.H-1, p.Y+1)
-			case "left":
-				nx = max(0, p.X-1)
-			case "right":
-				nx = min(g.W-1, p.X+1)
+	// Resolve intents simultaneously: round r applies the r-th move of every player,
+	// in this tick's priority order. Who wins a contested cell or sweet therefore
+	// depends on the priority rotation, not on which message arrived first.
+	order := g.priorityLocked()
+	for round := 0; round < MaxMovesPerTick; round++ {
+		for _, id := range order {
+			p := g.players[id]
+			// blocked moves do not count against the limit, try the next one
+			for len(queues[id]) > 0 {
+				c := queues[id][0]
+				queues[id] = queues[id][1:]
+				commandsProcessed.Inc()
+				if g.applyMove(p, c) {
+					break
+				}
 			}
 		}
+	}
+	for _, q := range queues {
+		commandsDropped.With("move_limit").Add(uint64(len(q))) // over MaxMovesPerTick
+	}
+}
 
-		// Check for collisions with other players
-		collision := false
-		for _, other := range g.players {
-			if other.ID != p.ID && other.X == nx && other.Y == ny {
-				collision = true
-				break
-			}
+// priorityLocked returns the player IDs in this tick's priority order:
+// sorted by ID, then rotated by the tick number so that each player gets first pick in turn.
+// Caller must hold g.mu.
+func (g *Game) priorityLocked() []string {
+	ids := make([]string, 0, len(g.players))
+	for id := range g.players {
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return ids
+	}
+	sort.Strings(ids)
+	offset := int(g.tick % int64(len(ids)))
+	return append(ids[offset:], ids[:offset]...)
+}
+
+// applyMove moves p according to c if the target cell is free and collects the sweet there.
+// It returns false if the move was blocked. Caller must hold g.mu.
+func (g *Game) applyMove(p *Player, c Command) bool {
+	// Compute new position
+	nx, ny := p.X, p.Y
+	switch c.Type {
+	case "move":
+		switch c.Dir {
+		case "up":
+			ny = max(0, p.Y-1)
+		case "down":
+			ny = min(g.H-1, p.Y+1)
+		case "left":
+			nx = max(0, p.X-1)
+		case "right":
+			nx = min(g.W-1, p.X+1)
 		}
+	}
 
-		// If no collision, apply move
-		if !collision {
-			p.X, p.Y = nx, ny
-			movesCount[c.PlayerID]++
-
-			// Check for sweet collection
-			for sid, s := range g.sweets {
-				if s.X == p.X && s.Y == p.Y {
-					p.Score++
-					delete(g.sweets, sid)
-					// broadcast event
-					evt := map[string]interface{}{"type": "event", "event": "collected", "player": p.ID, "sweet": sid, "tick": g.tick}
-					if b, err := json.Marshal(evt); err == nil {
-						select {
-						case g.EventBroadcast <- b:
-						default:
-						}
-					}
-					break
-				}
+	// Walls block the move
+	if g.grid.wall(nx, ny) {
+		return false
+	}
+
+	// Check for collisions with other players
+	if other := g.grid.playerAt(nx, ny); other != nil && other != p {
+		return false
+	}
+
+	// No collision, apply move
+	g.grid.movePlayer(p, nx, ny)
+
+	// Check for sweet collection
+	if s := g.grid.sweetAt(p.X, p.Y); s != nil {
+		p.Score++
+		g.grid.removeSweet(s)
+		delete(g.sweets, s.ID)
+		g.scheduleRespawnLocked(s.ID)
+		// broadcast event
+		evt := map[string]interface{}{"type": "event", "event": "collected", "player": p.ID, "sweet": s.ID, "tick": g.tick}
+		if b, err := json.Marshal(evt); err == nil {
+			select {
+			case g.EventBroadcast <- b:
+			default:
+				broadcastsDropped.With("event").Inc()
 			}
 		}
 	}
+	return true
 }
 
 func (g *Game) broadcastState() {
@@ -231,10 +394,16 @@ func (g *Game) broadcastState() {
 		// Create a copy of the sweet
 		sweets = append(sweets, &Sweet{ID: s.ID, X: s.X, Y: s.Y})
 	}
+	tick := g.tick
+	endsIn := g.roundEndsInLocked()
+	phase := "playing"
+	if g.paused {
+		phase = "paused"
+	}
 	// Unlock before marshaling to avoid holding lock too long
 	g.mu.Unlock()
 
-	msg := StateMessage{Type: "state", Tick: g.tick, Players: players, Sweets: sweets}
+	msg := StateMessage{Type: "state", Tick: tick, Players: players, Sweets: sweets, RoundEndsIn: endsIn, Phase: phase}
 	b, _ := json.Marshal(msg)
 
 	// Sending no blocking to avoid slowing down the game loop
@@ -242,83 +411,85 @@ func (g *Game) broadcastState() {
 	case g.StateBroadcast <- b:
 	default:
 		// drop if nobody consumes or backlog full
+		broadcastsDropped.With("state").Inc()
 	}
 }
 
-// AddPlayer adds a player at a random free position and returns id and pointer to player.
+// AddPlayer adds a player at a free position (see spawnLocked) and returns a pointer to the player.
+// It returns nil if there is no space left.
 func (g *Game) AddPlayer(name string) *Player {
 	// Lock to avoid players appear at the same position
 	g.mu.Lock()
 	defer g.mu.Unlock()
-	id := fmt.Sprintf("p-%d", len(g.players)+1)
-	// find free spot 
-	for i := 0; i < 1000; i++ {
-		x := g.rand.Intn(g.W)
-		y := g.rand.Intn(g.H)
-		free := true
-		for _, p := range g.players {
-			if p.X == x && p.Y == y {
-				free = false
-				break
-			}
-		}
-		if free {
-			p := &Player{ID: id, Name: name, X: x, Y: y, Score: 0}
-			g.players[id] = p
-			return p
-		}
+	return g.addPlayerLocked(name)
+}
+
+// Join adds a player for a client: the name is normalized and must pass ValidateName
+// and be unique in the game (case-insensitive). Errors are *JoinError.
+func (g *Game) Join(name string) (*Player, error) {
+	name = NormalizeName(name)
+	if err := ValidateName(name); err != nil {
+		return nil, err
 	}
-	// fallback: find first free in grid
-	// for the case of a nearly full grid and previous method does not find a spot
-	for y := 0; y < g.H; y++ {
-		for x := 0; x < g.W; x++ {
-			free := true
-			for _, p := range g.players {
-				if p.X == x && p.Y == y {
-					free = false
-					break
-				}
-			}
-			if free {
-				id := fmt.Sprintf("p-%d", len(g.players)+1)
-				p := &Player{ID: id, Name: name, X: x, Y: y, Score: 0}
-				g.players[id] = p
-				return p
-			}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, p := range g.players {
+		if strings.EqualFold(p.Name, name) {
+			return nil, ErrNameTaken
 		}
 	}
-	// no space left
-	return nil
+	p := g.addPlayerLocked(name)
+	if p == nil {
+		return nil, ErrBoardFull
+	}
+	return p, nil
+}
+
+// addPlayerLocked spawns a player, nil if the board is full. Caller must hold g.mu.
+func (g *Game) addPlayerLocked(name string) *Player {
+	x, y, ok := g.spawnLocked()
+	if !ok {
+		return nil
+	}
+	// ids are never reused, even after a player left
+	g.nextID++
+	id := fmt.Sprintf("p-%d", g.nextID)
+	p := &Player{ID: id, Name: name, X: x, Y: y, Score: 0}
+	g.players[id] = p
+	g.grid.addPlayer(p)
+	g.record(RecordEntry{Kind: "join", ID: id, Name: name})
+	if g.paused && g.pauseReason == PauseEmpty {
+		g.resumeLocked()
+	}
+	return p
 }
 
 // Restart resets the game state for a new round.
 func (g *Game) Restart() {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	if g.nextRules != nil {
+		// recorded before the restart, that the replay places as many sweets
+		g.rules, g.nextRules = *g.nextRules, nil
+		g.record(RecordEntry{Kind: "rules", Rules: &g.rules})
+	}
+	g.record(RecordEntry{Kind: "restart"})
 
 	// Reset Scores
 	for _, p := range g.players {
 		p.Score = 0
 	}
 
-	// Regen Sweets (20 sweets)
+	// Regen Sweets (20 sweets by default)
 	g.sweets = make(map[string]*Sweet)
-	for i := 0; i < 20; i++ {
-		x := g.rand.Intn(g.W)
-		y := g.rand.Intn(g.H)
-		id := fmt.Sprintf("s%d", i+1)
-		g.sweets[id] = &Sweet{ID: id, X: x, Y: y}
-	}
+	g.grid.clearSweets()
+	g.placeSweetsLocked(g.rules.Sweets)
+	g.respawns = nil
+	g.roundStart = g.tick
+	g.endRound = false
 
 	// Clear pending commands
-LOOP:
-	for {
-		select {
-		case <-g.commands:
-		default:
-			break LOOP
-		}
-	}
+	g.drainCommands()
 }
 
 // Testing helpers (exported) -------------------------------------------------
@@ -327,7 +498,8 @@ LOOP:
 func (g *Game) SetSweet(id string, x, y int) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
-	g.sweets[id] = &Sweet{ID: id, X: x, Y: y}
+	g.placeSweetLocked(&Sweet{ID: id, X: x, Y: y})
+	g.record(RecordEntry{Kind: "set_sweet", ID: id, X: x, Y: y})
 }
 
 // ClearSweets removes all sweets (useful for tests).
@@ -335,16 +507,28 @@ func (g *Game) ClearSweets() {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 	g.sweets = make(map[string]*Sweet)
+	g.grid.clearSweets()
+	g.record(RecordEntry{Kind: "clear_sweets"})
 }
 
-// SetPlayerPosition moves a player instantly (for test setup).
-func (g *Game) SetPlayerPosition(id string, x, y int) {
+// SetPlayerPosition moves a player instantly (for test setup). As PlaceSweet, it refuses
+// a cell outside the board, a wall or a cell taken by another player. An unknown player is ignored.
+func (g *Game) SetPlayerPosition(id string, x, y int) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
-	if p, ok := g.players[id]; ok {
-		p.X = x
-		p.Y = y
+	p, ok := g.players[id]
+	if !ok {
+		return nil
 	}
+	if g.grid.cell(x, y) < 0 {
+		return ErrOutOfBoard
+	}
+	if o := g.grid.playerAt(x, y); g.grid.wall(x, y) || (o != nil && o != p) {
+		return ErrCellTaken
+	}
+	g.grid.movePlayer(p, x, y)
+	g.record(RecordEntry{Kind: "set_position", ID: id, X: x, Y: y})
+	return nil
 }
 
 // GetPlayer returns a copy of the player state (nil if not found).
@@ -358,6 +542,36 @@ func (g *Game) GetPlayer(id string) *Player {
 	return nil
 }
 
+// placeSweetLocked adds s to the board, replacing any sweet with the same ID. Caller must hold g.mu.
+func (g *Game) placeSweetLocked(s *Sweet) {
+	if old, ok := g.sweets[s.ID]; ok {
+		g.grid.removeSweet(old)
+	}
+	g.sweets[s.ID] = s
+	g.grid.addSweet(s)
+}
+
+// OnRoundEnd sets the function called with the final scores at the end of each round.
+func (g *Game) OnRoundEnd(f func(scores []Score)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.onRoundEnd = f
+}
+
+// SetLogContext sets the attributes added to the logs of the game (e.g. "room", id).
+func (g *Game) SetLogContext(args ...any) {
+	g.loop.mu.Lock()
+	defer g.loop.mu.Unlock()
+	g.loop.logCtx = args[:len(args):len(args)] // appending to it must copy
+}
+
+// Tick returns the current tick number.
+func (g *Game) Tick() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.tick
+}
+
 // SweetsCount returns the number of sweets remaining.
 func (g *Game) SweetsCount() int {
 	g.mu.Lock()
@@ -365,20 +579,44 @@ func (g *Game) SweetsCount() int {
 	return len(g.sweets)
 }
 
+// PlayersCount returns the number of players in the game.
+func (g *Game) PlayersCount() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.players)
+}
+
 // RemovePlayer removes a player from the game state.
 func (g *Game) RemovePlayer(id string) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	if p, ok := g.players[id]; ok {
+		g.grid.removePlayer(p)
+		g.record(RecordEntry{Kind: "leave", ID: id})
+	}
 	delete(g.players, id)
+	if g.autoPause && len(g.players) == 0 {
+		g.pauseLocked(PauseEmpty)
+	}
+	g.qmu.Lock()
+	delete(g.queues, id)
+	g.qmu.Unlock()
 }
 
-// PushCommand queues a command.
-func (g *Game) PushCommand(c Command) {
-	select {
-	case g.commands <- c:
-	default:
-		// drop if full
+// MaxQueuedCommands is the number of commands a player can have waiting for the next tick.
+const MaxQueuedCommands = 8
+
+// PushCommand queues a command. It returns false if the queue of the player is full
+// and the command was dropped (other players are not affected).
+func (g *Game) PushCommand(c Command) bool {
+	g.qmu.Lock()
+	defer g.qmu.Unlock()
+	if len(g.queues[c.PlayerID]) >= MaxQueuedCommands {
+		commandsDropped.With("queue_full").Inc()
+		return false
 	}
+	g.queues[c.PlayerID] = append(g.queues[c.PlayerID], c)
+	return true
 }
 
 // helpers
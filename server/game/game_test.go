@@ -21,6 +21,7 @@ func TestMoveAndCollision(t *testing.T) {
 	// place two players deterministically
 	g.mu.Lock()
 	g.players = map[string]*Player{"p-1": {ID: "p-1", Name: "A", X: 1, Y: 1, Score: 0}, "p-2": {ID: "p-2", Name: "B", X: 2, Y: 1, Score: 0}}
+	g.reindexLocked()
 	g.mu.Unlock()
 	// p-1 moves right into occupied cell (2,1) — should be blocked
 	g.PushCommand(Command{PlayerID: "p-1", Type: "move", Dir: "right"})
@@ -37,6 +38,7 @@ func TestCollectSweet(t *testing.T) {
 	g.mu.Lock()
 	g.players = map[string]*Player{"p-1": {ID: "p-1", Name: "A", X: 0, Y: 0, Score: 0}}
 	g.sweets = map[string]*Sweet{"s1": {ID: "s1", X: 1, Y: 0}}
+	g.reindexLocked()
 	g.mu.Unlock()
 	// move right to collect
 	g.PushCommand(Command{PlayerID: "p-1", Type: "move", Dir: "right"})
@@ -92,16 +94,17 @@ func TestConflictCollect(t *testing.T) {
 		"p-2": {ID: "p-2", Name: "B", X: 2, Y: 1, Score: 0},
 	}
 	g.sweets = map[string]*Sweet{"s1": {ID: "s1", X: 1, Y: 1}}
+	g.reindexLocked()
 	g.mu.Unlock()
 
-	// Both move towards (1,1) in the same tick: p1 then p2 (p1 arrived first)
+	// Both move towards (1,1) in the same tick
 	g.PushCommand(Command{PlayerID: "p-1", Type: "move", Dir: "right"})
 	g.PushCommand(Command{PlayerID: "p-2", Type: "move", Dir: "left"})
 	g.applyCommands()
 
 	p1 := g.players["p-1"]
 	p2 := g.players["p-2"]
-	// since p-1's command arrived first, p-1 should win and collect the sweet
+	// at tick 0 the priority order is p-1, p-2: p-1 should win and collect the sweet
 	if p1.X != 1 || p1.Y != 1 {
 		t.Fatalf("expected p-1 at 1,1 got %d,%d", p1.X, p1.Y)
 	}
@@ -120,8 +123,8 @@ func TestConflictCollect(t *testing.T) {
 	}
 }
 
-func TestConflictArrivalOrder(t *testing.T) {
-	// same setup but push p2 then p1 to ensure arrival order gives p2 the sweet
+// conflictGame sets up p-1 and p-2 on each side of a sweet at (1,1).
+func conflictGame(tick int64) *Game {
 	g := NewGame(3, 3, 0)
 	g.mu.Lock()
 	g.players = map[string]*Player{
@@ -129,30 +132,86 @@ func TestConflictArrivalOrder(t *testing.T) {
 		"p-2": {ID: "p-2", Name: "B", X: 2, Y: 1, Score: 0},
 	}
 	g.sweets = map[string]*Sweet{"s1": {ID: "s1", X: 1, Y: 1}}
+	g.tick = tick
+	g.reindexLocked()
 	g.mu.Unlock()
+	return g
+}
 
-	// p2 command arrives first, then p1
+func TestConflictIgnoresArrivalOrder(t *testing.T) {
+	// same setup but push p2 then p1: arrival order must not change the winner
+	g := conflictGame(0)
 	g.PushCommand(Command{PlayerID: "p-2", Type: "move", Dir: "left"})
 	g.PushCommand(Command{PlayerID: "p-1", Type: "move", Dir: "right"})
 	g.applyCommands()
 
 	p1 := g.players["p-1"]
 	p2 := g.players["p-2"]
-	// p2 should win due to arrival order
-	if p2.X != 1 || p2.Y != 1 {
-		t.Fatalf("expected p-2 at 1,1 got %d,%d", p2.X, p2.Y)
-	}
-	if p2.Score != 1 {
-		t.Fatalf("expected p-2 score 1 got %d", p2.Score)
+	// p1 has priority at tick 0 even though its command arrived last
+	if p1.X != 1 || p1.Y != 1 || p1.Score != 1 {
+		t.Fatalf("expected p-1 at 1,1 with score 1 got %+v", p1)
 	}
-	// p1 should be blocked
-	if p1.X != 0 || p1.Y != 1 {
-		t.Fatalf("expected p-1 to stay at 0,1 got %d,%d", p1.X, p1.Y)
-	}
-	if p1.Score != 0 {
-		t.Fatalf("expected p-1 score 0 got %d", p1.Score)
+	// p2 should be blocked
+	if p2.X != 2 || p2.Y != 1 || p2.Score != 0 {
+		t.Fatalf("expected p-2 to stay at 2,1 with score 0 got %+v", p2)
 	}
 	if len(g.sweets) != 0 {
 		t.Fatalf("expected sweets empty, got %d", len(g.sweets))
 	}
-}
\ No newline at end of file
+}
+
+func TestConflictPriorityRotates(t *testing.T) {
+	// at tick 1 the priority rotates: p2 picks first whatever the arrival order
+	g := conflictGame(1)
+	g.PushCommand(Command{PlayerID: "p-1", Type: "move", Dir: "right"})
+	g.PushCommand(Command{PlayerID: "p-2", Type: "move", Dir: "left"})
+	g.applyCommands()
+
+	p1 := g.players["p-1"]
+	p2 := g.players["p-2"]
+	if p2.X != 1 || p2.Score != 1 {
+		t.Fatalf("expected p-2 to collect at tick 1, got %+v", p2)
+	}
+	if p1.X != 0 || p1.Score != 0 {
+		t.Fatalf("expected p-1 to be blocked at tick 1, got %+v", p1)
+	}
+}
+
+func TestMoveLimitPerTick(t *testing.T) {
+	// a player spamming moves only moves MaxMovesPerTick cells, blocked moves do not count
+	g := NewGame(5, 1, 0)
+	g.mu.Lock()
+	g.players = map[string]*Player{"p-1": {ID: "p-1", Name: "A", X: 0, Y: 0}}
+	g.reindexLocked()
+	g.mu.Unlock()
+	g.PushCommand(Command{PlayerID: "p-1", Type: "move", Dir: "left"}) // stays at 0, still a valid move
+	for i := 0; i < 4; i++ {
+		g.PushCommand(Command{PlayerID: "p-1", Type: "move", Dir: "right"})
+	}
+	g.applyCommands()
+	if x := g.players["p-1"].X; x != 1 {
+		t.Fatalf("expected p-1 at x=1 after 2 moves, got %d", x)
+	}
+}
+
+func TestCommandQueuePerPlayer(t *testing.T) {
+	// a player filling his queue does not drop the commands of the others
+	g := NewGame(5, 5, 0)
+	for i := 0; i < MaxQueuedCommands; i++ {
+		if !g.PushCommand(Command{PlayerID: "p-1", Type: "move", Dir: "right"}) {
+			t.Fatalf("command %d refused", i)
+		}
+	}
+	if g.PushCommand(Command{PlayerID: "p-1", Type: "move", Dir: "right"}) {
+		t.Fatalf("command over the queue size accepted")
+	}
+	if !g.PushCommand(Command{PlayerID: "p-2", Type: "move", Dir: "left"}) {
+		t.Fatalf("other player's command refused")
+	}
+	if cmds := g.drainCommands(); len(cmds) != MaxQueuedCommands+1 {
+		t.Fatalf("expected %d commands, got %d", MaxQueuedCommands+1, len(cmds))
+	}
+	if !g.PushCommand(Command{PlayerID: "p-1", Type: "move", Dir: "right"}) {
+		t.Fatalf("queue not emptied by the tick")
+	}
+}
@@ -0,0 +1,168 @@
+package game
+
+// grid is an occupancy index of the board: which player and which sweets are on each cell.
+// It gives O(1) lookups for collisions and sweet pickup instead of scanning every player/sweet.
+// It must be kept in sync with g.players and g.sweets, always under g.mu.
+type grid struct {
+	w, h    int
+	players []*Player  // one slot per cell, nil if free
+	sweets  [][]*Sweet // sweets on each cell (usually zero or one)
+	walls   []bool     // cells nobody can enter
+	// set of cells without player, to pick a spawn uniformly in O(1)
+	free    []int // cell indexes
+	freePos []int // position of each cell in free, -1 if occupied
+}
+
+func newGrid(w, h int) *grid {
+	gr := &grid{w: w, h: h, players: make([]*Player, w*h), sweets: make([][]*Sweet, w*h), walls: make([]bool, w*h), free: make([]int, w*h), freePos: make([]int, w*h)}
+	for i := range gr.free {
+		gr.free[i] = i
+		gr.freePos[i] = i
+	}
+	return gr
+}
+
+// cell returns the index of (x,y), or -1 if out of the board.
+func (gr *grid) cell(x, y int) int {
+	if x < 0 || y < 0 || x >= gr.w || y >= gr.h {
+		return -1
+	}
+	return y*gr.w + x
+}
+
+// playerAt returns the player on (x,y), nil if free.
+func (gr *grid) playerAt(x, y int) *Player {
+	if i := gr.cell(x, y); i >= 0 {
+		return gr.players[i]
+	}
+	return nil
+}
+
+func (gr *grid) addPlayer(p *Player) {
+	if i := gr.cell(p.X, p.Y); i >= 0 {
+		gr.players[i] = p
+		gr.markOccupied(i)
+	}
+}
+
+func (gr *grid) removePlayer(p *Player) {
+	if i := gr.cell(p.X, p.Y); i >= 0 && gr.players[i] == p {
+		gr.players[i] = nil
+		gr.markFree(i)
+	}
+}
+
+// markOccupied removes cell i from the free set (swap with the last one).
+func (gr *grid) markOccupied(i int) {
+	k := gr.freePos[i]
+	if k < 0 {
+		return
+	}
+	last := gr.free[len(gr.free)-1]
+	gr.free[k] = last
+	gr.freePos[last] = k
+	gr.free = gr.free[:len(gr.free)-1]
+	gr.freePos[i] = -1
+}
+
+func (gr *grid) markFree(i int) {
+	if gr.freePos[i] >= 0 || gr.walls[i] {
+		return
+	}
+	gr.freePos[i] = len(gr.free)
+	gr.free = append(gr.free, i)
+}
+
+// wall reports whether (x,y) is a wall. Out of the board counts as a wall.
+func (gr *grid) wall(x, y int) bool {
+	i := gr.cell(x, y)
+	return i < 0 || gr.walls[i]
+}
+
+// setWall turns cell i into a wall, it is no longer free.
+func (gr *grid) setWall(i int) {
+	gr.walls[i] = true
+	gr.markOccupied(i)
+}
+
+// nearestPlayer returns the distance (in rings around the cell) to the closest player,
+// looking at most limit cells away. It returns limit+1 if nobody is that close.
+func (gr *grid) nearestPlayer(x, y, limit int) int {
+	return gr.nearest(x, y, limit, func(i int) bool { return gr.players[i] != nil })
+}
+
+// nearestSweet is like nearestPlayer for sweets.
+func (gr *grid) nearestSweet(x, y, limit int) int {
+	return gr.nearest(x, y, limit, func(i int) bool { return len(gr.sweets[i]) > 0 })
+}
+
+func (gr *grid) nearest(x, y, limit int, found func(i int) bool) int {
+	for r := 1; r <= limit; r++ {
+		for dy := -r; dy <= r; dy++ {
+			for dx := -r; dx <= r; dx++ {
+				if dx != -r && dx != r && dy != -r && dy != r {
+					continue // inside the ring, already checked
+				}
+				if i := gr.cell(x+dx, y+dy); i >= 0 && found(i) {
+					return r
+				}
+			}
+		}
+	}
+	return limit + 1
+}
+
+// movePlayer moves p to (x,y) and updates the index.
+func (gr *grid) movePlayer(p *Player, x, y int) {
+	gr.removePlayer(p)
+	p.X, p.Y = x, y
+	gr.addPlayer(p)
+}
+
+// sweetAt returns a sweet on (x,y), nil if none.
+func (gr *grid) sweetAt(x, y int) *Sweet {
+	if i := gr.cell(x, y); i >= 0 && len(gr.sweets[i]) > 0 {
+		return gr.sweets[i][0]
+	}
+	return nil
+}
+
+func (gr *grid) addSweet(s *Sweet) {
+	if i := gr.cell(s.X, s.Y); i >= 0 {
+		gr.sweets[i] = append(gr.sweets[i], s)
+	}
+}
+
+func (gr *grid) removeSweet(s *Sweet) {
+	i := gr.cell(s.X, s.Y)
+	if i < 0 {
+		return
+	}
+	for k, o := range gr.sweets[i] {
+		if o == s {
+			gr.sweets[i] = append(gr.sweets[i][:k], gr.sweets[i][k+1:]...)
+			return
+		}
+	}
+}
+
+func (gr *grid) clearSweets() {
+	gr.sweets = make([][]*Sweet, gr.w*gr.h)
+}
+
+// reindexLocked rebuilds the grid from g.players and g.sweets. Caller must hold g.mu.
+func (g *Game) reindexLocked() {
+	old := g.grid
+	g.grid = newGrid(g.W, g.H)
+	for i, w := range old.walls {
+		if w {
+			g.grid.setWall(i)
+		}
+	}
+	for _, p := range g.players {
+		g.grid.addPlayer(p)
+	}
+	for _, s := range g.sweets {
+		g.grid.addSweet(s)
+	}
+}
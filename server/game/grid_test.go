@@ -0,0 +1,167 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+)
+
+// checkGrid verifies the occupancy index matches g.players and g.sweets.
+func checkGrid(t *testing.T, g *Game) {
+	t.Helper()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, p := range g.players {
+		if g.grid.playerAt(p.X, p.Y) != p {
+			t.Fatalf("player %s missing from grid at %d,%d", p.ID, p.X, p.Y)
+		}
+	}
+	n := 0
+	for i, cell := range g.grid.players {
+		if cell != nil {
+			n++
+			if g.players[cell.ID] != cell || g.grid.cell(cell.X, cell.Y) != i {
+				t.Fatalf("stale player %s in grid", cell.ID)
+			}
+		}
+	}
+	if n != len(g.players) {
+		t.Fatalf("grid has %d players, game has %d", n, len(g.players))
+	}
+	n = 0
+	for _, cell := range g.grid.sweets {
+		for _, s := range cell {
+			n++
+			if g.sweets[s.ID] != s {
+				t.Fatalf("stale sweet %s in grid", s.ID)
+			}
+		}
+	}
+	if n != len(g.sweets) {
+		t.Fatalf("grid has %d sweets, game has %d", n, len(g.sweets))
+	}
+}
+
+func TestGridStaysInSync(t *testing.T) {
+	g := NewGame(6, 6, 10)
+	checkGrid(t, g)
+	a := g.AddPlayer("A")
+	g.SetPlayerPosition(a.ID, 0, 0)
+	b := g.AddPlayer("B") // spawns on a free cell, not on a's
+	g.SetPlayerPosition(b.ID, 1, 0)
+	g.SetSweet("x", 0, 1)
+	g.SetSweet("x", 0, 2) // replace: moves the sweet
+	checkGrid(t, g)
+
+	g.PushCommand(Command{PlayerID: a.ID, Type: "move", Dir: "right"}) // blocked by b
+	g.PushCommand(Command{PlayerID: a.ID, Type: "move", Dir: "down"})
+	g.PushCommand(Command{PlayerID: a.ID, Type: "move", Dir: "down"}) // collects x
+	g.applyCommands()
+	checkGrid(t, g)
+	if p := g.GetPlayer(a.ID); p.X != 0 || p.Y != 2 || p.Score < 1 {
+		t.Fatalf("unexpected player state %+v", p)
+	}
+
+	g.RemovePlayer(b.ID)
+	g.Restart()
+	checkGrid(t, g)
+	g.ClearSweets()
+	checkGrid(t, g)
+}
+
+func TestSetPlayerPositionChecked(t *testing.T) {
+	g := NewGame(4, 4, 0)
+	g.SetWalls([]Pos{{3, 3}})
+	a := g.AddPlayer("A")
+	if err := g.SetPlayerPosition(a.ID, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	b := g.AddPlayer("B")
+	if err := g.SetPlayerPosition(b.ID, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetPlayerPosition(b.ID, 1, 0); err != nil {
+		t.Fatalf("staying on its own cell refused: %v", err)
+	}
+	for _, c := range []struct {
+		x, y int
+		err  error
+	}{{0, 0, ErrCellTaken}, {3, 3, ErrCellTaken}, {4, 0, ErrOutOfBoard}, {0, -1, ErrOutOfBoard}} {
+		if err := g.SetPlayerPosition(b.ID, c.x, c.y); err != c.err {
+			t.Fatalf("%d,%d: expected %v, got %v", c.x, c.y, c.err, err)
+		}
+	}
+	if p := g.GetPlayer(b.ID); p.X != 1 || p.Y != 0 {
+		t.Fatalf("refused move applied: %+v", p)
+	}
+	checkGrid(t, g)
+}
+
+// crowdedGame returns a 100x100 game with n players and one move queued per player.
+func crowdedGame(n int) *Game {
+	g := NewGame(100, 100, 500)
+	for i := 0; i < n; i++ {
+		g.AddPlayer(fmt.Sprintf("bot-%d", i))
+	}
+	return g
+}
+
+func queueMoves(g *Game, dirs []string, i int) {
+	g.mu.Lock()
+	ids := make([]string, 0, len(g.players))
+	for id := range g.players {
+		ids = append(ids, id)
+	}
+	g.mu.Unlock()
+	for k, id := range ids {
+		g.PushCommand(Command{PlayerID: id, Type: "move", Dir: dirs[(i+k)%len(dirs)]})
+	}
+}
+
+// BenchmarkApplyCommands250Players runs a full tick with 250 players moving on a 100x100 board.
+func BenchmarkApplyCommands250Players(b *testing.B) {
+	g := crowdedGame(250)
+	dirs := []string{"up", "right", "down", "left"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		queueMoves(g, dirs, i)
+		b.StartTimer()
+		g.applyCommands()
+	}
+}
+
+// The two benchmarks below compare the lookups done for each move:
+// the former linear scan of every player and sweet versus the occupancy grid.
+
+func BenchmarkOccupancyLinearScan(b *testing.B) {
+	g := crowdedGame(250)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x, y := i%g.W, (i/g.W)%g.H
+		occupied := false
+		for _, p := range g.players {
+			if p.X == x && p.Y == y {
+				occupied = true
+				break
+			}
+		}
+		if !occupied {
+			for _, s := range g.sweets {
+				if s.X == x && s.Y == y {
+					break
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkOccupancyGrid(b *testing.B) {
+	g := crowdedGame(250)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x, y := i%g.W, (i/g.W)%g.H
+		if g.grid.playerAt(x, y) == nil {
+			g.grid.sweetAt(x, y)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package game
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/metrics"
+)
+
+var (
+	tickOverruns = metrics.NewCounter("game_tick_overruns_total", "Ticks that took longer than the tick period.")
+	ticksSkipped = metrics.NewCounter("game_ticks_skipped_total", "Ticks dropped by the ticker because the loop was late.")
+)
+
+// Adaptive tick rate: under a sustained load the loop slows down (never below
+// the minimum set by SetAdaptiveTPS), and speeds up again once the load is gone.
+const (
+	loadAlpha      = 0.05            // weight of the last tick in the load average
+	highLoad       = 0.8             // share of the tick period used, above which the loop slows down
+	lowLoad        = 0.3             // below which it goes back towards the base rate
+	adaptEvery     = 2 * time.Second // minimum time between two rate changes
+	overrunWarnGap = 10 * time.Second
+)
+
+// LoopStats describe the health of a game loop.
+type LoopStats struct {
+	Running  bool    `json:"running"`
+	TPS      int     `json:"tps"`      // current tick rate
+	BaseTPS  int     `json:"base_tps"` // rate given to Start
+	Ticks    int64   `json:"ticks"`
+	Overruns uint64  `json:"overruns"` // ticks longer than the period
+	Skipped  uint64  `json:"skipped"`  // ticks the ticker dropped because we were late
+	LastTick float64 `json:"last_tick_ms"`
+	MaxTick  float64 `json:"max_tick_ms"`
+	Load     float64 `json:"load"` // moving average of tick time / tick period, above 1 the loop cannot keep up
+	Adaptive bool    `json:"adaptive"`
+	MinTPS   int     `json:"min_tps,omitempty"`
+	// the loop does not tick during the pause between two rounds
+	LastTickAt    time.Time `json:"last_tick_at"`
+	BetweenRounds bool      `json:"between_rounds"`
+}
+
+// loopStats are updated by the loop goroutine after each tick.
+type loopStats struct {
+	mu        sync.Mutex
+	stats     LoopStats
+	lastWarn  time.Time
+	warnOver  uint64 // overruns since the last warning
+	warnSkip  uint64
+	lastAdapt time.Time
+	logCtx    []any // see SetLogContext
+}
+
+// SetAdaptiveTPS lets the loop lower its tick rate down to minTPS under load (0 disables it).
+// Durations in ticks (endless mode) then last longer in real time.
+func (g *Game) SetAdaptiveTPS(minTPS int) {
+	g.loop.mu.Lock()
+	defer g.loop.mu.Unlock()
+	g.loop.stats.Adaptive = minTPS > 0
+	g.loop.stats.MinTPS = minTPS
+}
+
+// LoopStats returns the health of the game loop.
+func (g *Game) LoopStats() LoopStats {
+	g.loop.mu.Lock()
+	defer g.loop.mu.Unlock()
+	return g.loop.stats
+}
+
+// start resets the stats when the loop starts at tps.
+func (l *loopStats) start(tps int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stats.Running = true
+	l.stats.TPS, l.stats.BaseTPS = tps, tps
+	l.stats.Load = 0
+	l.stats.LastTickAt = time.Now() // not stalled before the first tick
+	l.lastAdapt = time.Now()
+}
+
+func (l *loopStats) stop() {
+	l.mu.Lock()
+	l.stats.Running = false
+	l.mu.Unlock()
+}
+
+func (l *loopStats) betweenRounds(b bool) {
+	l.mu.Lock()
+	l.stats.BetweenRounds = b
+	l.mu.Unlock()
+}
+
+// observe records a tick that took d, after skipped dropped ticks, at the current period.
+// It returns the new tick rate if the loop must change it, 0 otherwise.
+func (l *loopStats) observe(d time.Duration, skipped int, period time.Duration) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := &l.stats
+	s.Ticks++
+	ms := float64(d) / float64(time.Millisecond)
+	s.LastTick = ms
+	if ms > s.MaxTick {
+		s.MaxTick = ms
+	}
+	s.Load += loadAlpha * (float64(d)/float64(period) - s.Load)
+	if d > period {
+		s.Overruns++
+		l.warnOver++
+		tickOverruns.Inc()
+	}
+	if skipped > 0 {
+		s.Skipped += uint64(skipped)
+		l.warnSkip += uint64(skipped)
+		ticksSkipped.Add(uint64(skipped))
+	}
+
+	now := time.Now()
+	s.LastTickAt = now
+	if (l.warnOver > 0 || l.warnSkip > 0) && now.Sub(l.lastWarn) >= overrunWarnGap {
+		slog.Warn("tick budget exceeded", append(l.logCtx, "last_tick_ms", ms, "period", period,
+			"overruns", l.warnOver, "skipped", l.warnSkip, "load", s.Load)...)
+		l.lastWarn, l.warnOver, l.warnSkip = now, 0, 0
+	}
+
+	if !s.Adaptive || now.Sub(l.lastAdapt) < adaptEvery {
+		return 0
+	}
+	tps := s.TPS
+	switch {
+	case s.Load > highLoad && tps > s.MinTPS:
+		tps = max(s.MinTPS, tps*3/4)
+	case s.Load < lowLoad && tps < s.BaseTPS:
+		tps = min(s.BaseTPS, tps*4/3+1)
+	default:
+		return 0
+	}
+	slog.Info("tick rate changed", append(l.logCtx, "load", s.Load, "from", s.TPS, "to", tps)...)
+	// the load was measured against the old period
+	s.Load *= float64(tps) / float64(s.TPS)
+	s.TPS = tps
+	l.lastAdapt = now
+	return tps
+}
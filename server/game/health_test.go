@@ -0,0 +1,48 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoopStats(t *testing.T) {
+	var l loopStats
+	l.start(20)
+	period := 50 * time.Millisecond
+
+	if tps := l.observe(10*time.Millisecond, 0, period); tps != 0 {
+		t.Fatalf("rate changed without adaptation: %d", tps)
+	}
+	l.observe(120*time.Millisecond, 2, period)
+	s := l.stats
+	if s.Ticks != 2 || s.Overruns != 1 || s.Skipped != 2 || s.MaxTick != 120 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+
+	// sustained load: the rate goes down, not below the minimum
+	l.stats.Adaptive, l.stats.MinTPS = true, 12
+	l.stats.Load = 0.9
+	l.lastAdapt = time.Time{}
+	if tps := l.observe(45*time.Millisecond, 0, period); tps != 15 {
+		t.Fatalf("expected 15 ticks/s under load, got %d", tps)
+	}
+	if tps := l.observe(45*time.Millisecond, 0, period); tps != 0 {
+		t.Fatalf("rate changed again too soon: %d", tps)
+	}
+	l.stats.Load = 0.9
+	l.lastAdapt = time.Time{}
+	if tps := l.observe(60*time.Millisecond, 0, time.Second/15); tps != 12 {
+		t.Fatalf("expected the minimum rate 12, got %d", tps)
+	}
+
+	// load gone: back to the base rate
+	l.stats.Load = 0
+	l.lastAdapt = time.Time{}
+	if tps := l.observe(time.Millisecond, 0, time.Second/12); tps != 17 {
+		t.Fatalf("expected 17 ticks/s, got %d", tps)
+	}
+	l.lastAdapt = time.Time{}
+	if tps := l.observe(time.Millisecond, 0, time.Second/17); tps != 20 {
+		t.Fatalf("expected the base rate 20, got %d", tps)
+	}
+}
@@ -0,0 +1,12 @@
+package game
+
+import "github.com/dntelisa/SR-S9-Projet-Serveur/server/metrics"
+
+// metrics of every game, exposed on /metrics
+var (
+	tickDuration = metrics.NewHistogram("game_tick_duration_seconds", "Time to process a tick (commands and state broadcast).",
+		[]float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1})
+	commandsProcessed = metrics.NewCounter("game_commands_processed_total", "Commands applied or tried by the game loop.")
+	commandsDropped   = metrics.NewCounterVec("game_commands_dropped_total", "Commands dropped before being applied.", "reason")
+	broadcastsDropped = metrics.NewCounterVec("game_broadcasts_dropped_total", "Messages dropped because the broadcast channel was full.", "kind")
+)
@@ -0,0 +1,117 @@
+package game
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// JoinError is returned when a player cannot join; Code is sent to the client.
+type JoinError struct {
+	Code    string
+	Message string
+}
+
+func (e *JoinError) Error() string { return e.Message }
+
+// MaxNameLen is the maximum length of a player name, in characters.
+const MaxNameLen = 16
+
+var (
+	ErrNameEmpty    = &JoinError{"name_empty", "name is empty"}
+	ErrNameTooLong  = &JoinError{"name_too_long", "name is longer than 16 characters"}
+	ErrNameCharset  = &JoinError{"name_invalid_chars", "name may only contain letters, digits, spaces, '-', '_' and '.'"}
+	ErrNameReserved = &JoinError{"name_reserved", "name is reserved"}
+	ErrNameBlocked  = &JoinError{"name_blocked", "name is not allowed"}
+	ErrNameTaken    = &JoinError{"name_taken", "name already used in this room"}
+	ErrBoardFull    = &JoinError{"board_full", "no free cell left on the board"}
+)
+
+// ReservedNames cannot be used by players (compared case-insensitively).
+var ReservedNames = []string{"admin", "moderator", "server", "spectator", "system"}
+
+var (
+	blockMu   sync.RWMutex
+	blockList []string // lower case, see SetBlockList
+)
+
+// SetBlockList replaces the words refused anywhere in a name.
+func SetBlockList(words []string) {
+	list := make([]string, 0, len(words))
+	for _, w := range words {
+		if w = foldName(w); w != "" {
+			list = append(list, w)
+		}
+	}
+	blockMu.Lock()
+	blockList = list
+	blockMu.Unlock()
+}
+
+// ReadBlockList reads one word per line, ignoring blank lines and "#" comments.
+func ReadBlockList(r io.Reader) ([]string, error) {
+	var words []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	return words, sc.Err()
+}
+
+// NormalizeName trims a name and collapses runs of spaces.
+func NormalizeName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// ValidateName checks a normalized name against the length, charset, reserved
+// names and block-list rules. Uniqueness is checked by Join, per game.
+func ValidateName(name string) error {
+	if name == "" {
+		return ErrNameEmpty
+	}
+	n := 0
+	for _, r := range name {
+		n++
+		if n > MaxNameLen {
+			return ErrNameTooLong
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(" -_.", r) {
+			return ErrNameCharset
+		}
+	}
+	for _, res := range ReservedNames {
+		if strings.EqualFold(name, res) {
+			return ErrNameReserved
+		}
+	}
+	folded := foldName(name)
+	blockMu.RLock()
+	defer blockMu.RUnlock()
+	for _, w := range blockList {
+		if strings.Contains(folded, w) {
+			return ErrNameBlocked
+		}
+	}
+	return nil
+}
+
+// leet maps the usual digit substitutions back to letters for the block-list.
+var leet = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t")
+
+// foldName lower-cases name, undoes leet speak and drops separators,
+// so that "B.a_d" or "b4d" match the blocked word "bad".
+func foldName(name string) string {
+	name = leet.Replace(strings.ToLower(name))
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, name)
+}
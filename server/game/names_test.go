@@ -0,0 +1,58 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateName(t *testing.T) {
+	defer SetBlockList(nil)
+	SetBlockList([]string{"Bad"})
+	cases := []struct {
+		name string
+		err  error
+	}{
+		{"Alice", nil},
+		{"Jean-Luc_2.0", nil},
+		{"Élisa", nil},
+		{"", ErrNameEmpty},
+		{strings.Repeat("a", MaxNameLen), nil},
+		{strings.Repeat("a", MaxNameLen+1), ErrNameTooLong},
+		{"bell\a", ErrNameCharset},
+		{"<script>", ErrNameCharset},
+		{"Admin", ErrNameReserved},
+		{"SoBad", ErrNameBlocked},
+		{"b.a_d", ErrNameBlocked},
+		{"B4D", ErrNameBlocked},
+	}
+	for _, c := range cases {
+		if err := ValidateName(c.name); err != c.err {
+			t.Errorf("ValidateName(%q) = %v, expected %v", c.name, err, c.err)
+		}
+	}
+	if n := NormalizeName("  Jean   Luc "); n != "Jean Luc" {
+		t.Fatalf("unexpected normalized name %q", n)
+	}
+}
+
+func TestJoinUniqueName(t *testing.T) {
+	g := NewGame(2, 1, 0)
+	p, err := g.Join("  Alice ")
+	if err != nil || p.Name != "Alice" {
+		t.Fatalf("join: %v %+v", err, p)
+	}
+	if _, err := g.Join("alice"); err != ErrNameTaken {
+		t.Fatalf("expected ErrNameTaken, got %v", err)
+	}
+	if _, err := g.Join("Bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Join("Carol"); err != ErrBoardFull {
+		t.Fatalf("expected ErrBoardFull, got %v", err)
+	}
+	// the name is free again once the player left
+	g.RemovePlayer(p.ID)
+	if _, err := g.Join("ALICE"); err != nil {
+		t.Fatalf("name should be free after leaving: %v", err)
+	}
+}
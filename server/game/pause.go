@@ -0,0 +1,75 @@
+package game
+
+import "encoding/json"
+
+// Pause reasons, sent with the "paused" event.
+const (
+	PauseAdmin = "admin" // Pause was called, only Resume ends it
+	PauseEmpty = "empty" // the last player left (see SetAutoPause), the next join resumes
+)
+
+// Pause freezes the game: the loop keeps running but skips its ticks, so the tick
+// counter (and the endless mode timers) stop, and inputs sent meanwhile are dropped.
+// The clients get a "paused" event, then a state with phase "paused" every second.
+func (g *Game) Pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.pauseLocked(PauseAdmin)
+}
+
+// Resume restarts a paused game.
+func (g *Game) Resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.resumeLocked()
+}
+
+// Paused reports whether the game is paused.
+func (g *Game) Paused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.paused
+}
+
+// SetAutoPause makes the game pause when its last player leaves and resume when one joins.
+func (g *Game) SetAutoPause(on bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.autoPause = on
+	if on && len(g.players) == 0 && !g.paused {
+		g.pauseLocked(PauseEmpty)
+	}
+}
+
+// pauseLocked pauses the game for reason. An admin pause replaces an automatic one.
+// Caller must hold g.mu.
+func (g *Game) pauseLocked(reason string) {
+	if g.paused && (g.pauseReason == reason || reason == PauseEmpty) {
+		return
+	}
+	g.paused, g.pauseReason = true, reason
+	g.pausedSteps = 0
+	g.eventLocked(map[string]interface{}{"type": "event", "event": "paused", "reason": reason, "tick": g.tick})
+}
+
+// resumeLocked ends the pause. Caller must hold g.mu.
+func (g *Game) resumeLocked() {
+	if !g.paused {
+		return
+	}
+	g.paused, g.pauseReason = false, ""
+	g.eventLocked(map[string]interface{}{"type": "event", "event": "resumed", "tick": g.tick})
+}
+
+// eventLocked sends an event to the clients without blocking. Caller must hold g.mu.
+func (g *Game) eventLocked(evt map[string]interface{}) {
+	b, err := json.Marshal(evt)
+	if err != nil {
+		return
+	}
+	select {
+	case g.EventBroadcast <- b:
+	default:
+		broadcastsDropped.With("event").Inc()
+	}
+}
@@ -0,0 +1,71 @@
+package game
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// drain returns the messages waiting on ch.
+func drain(ch chan []byte) []map[string]interface{} {
+	var msgs []map[string]interface{}
+	for {
+		select {
+		case b := <-ch:
+			var m map[string]interface{}
+			json.Unmarshal(b, &m)
+			msgs = append(msgs, m)
+		default:
+			return msgs
+		}
+	}
+}
+
+func TestAutoPause(t *testing.T) {
+	g := NewGame(5, 5, 3)
+	g.tps = 4
+	g.SetAutoPause(true)
+	if !g.Paused() {
+		t.Fatal("an empty game with auto-pause should be paused")
+	}
+	if evts := drain(g.EventBroadcast); len(evts) != 1 || evts[0]["event"] != "paused" || evts[0]["reason"] != PauseEmpty {
+		t.Fatalf("unexpected events %v", evts)
+	}
+
+	// paused: frozen tick, one state per second (tps steps) with the phase
+	for i := 0; i < 8; i++ {
+		g.step()
+	}
+	states := drain(g.StateBroadcast)
+	if g.Tick() != 0 || len(states) != 2 || states[0]["phase"] != "paused" {
+		t.Fatalf("tick %d, states %v", g.Tick(), states)
+	}
+
+	// a join resumes, the last leave pauses again
+	p := g.AddPlayer("A")
+	if g.Paused() {
+		t.Fatal("join did not resume")
+	}
+	g.step()
+	if states := drain(g.StateBroadcast); g.Tick() != 1 || states[0]["phase"] != "playing" {
+		t.Fatalf("tick %d, states %v", g.Tick(), states)
+	}
+	g.RemovePlayer(p.ID)
+	if !g.Paused() {
+		t.Fatal("last leave did not pause")
+	}
+	evts := drain(g.EventBroadcast)
+	if len(evts) != 2 || evts[0]["event"] != "resumed" || evts[1]["event"] != "paused" {
+		t.Fatalf("unexpected events %v", evts)
+	}
+
+	// an admin pause is not ended by a join
+	g.Pause()
+	g.AddPlayer("B")
+	if !g.Paused() {
+		t.Fatal("join ended an admin pause")
+	}
+	g.Resume()
+	if g.Paused() {
+		t.Fatal("resume failed")
+	}
+}
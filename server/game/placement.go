@@ -0,0 +1,249 @@
+package game
+
+import "fmt"
+
+// SweetPlacement configures where sweets appear at the start of a round.
+// Sweets are never placed on a wall, under a player or on another sweet.
+type SweetPlacement struct {
+	Strategy   string `json:"strategy"`    // "uniform" (default), "clustered" or "symmetric"
+	MinSpacing int    `json:"min_spacing"` // minimum distance between two sweets (in cells), 0 for none
+	Regions    int    `json:"regions"`     // board split in Regions x Regions areas holding the same number of sweets (±1), 0 or 1 to disable
+	Clusters   int    `json:"clusters"`    // "clustered" only: number of clusters, default 3
+}
+
+// Placement strategies.
+const (
+	PlacementUniform   = "uniform"   // anywhere on the board
+	PlacementClustered = "clustered" // grouped around a few random centres
+	PlacementSymmetric = "symmetric" // by pairs mirrored around the board centre, no side is favoured
+)
+
+const placementAttempts = 50 // random draws before relaxing the spacing
+
+// Validate checks the placement settings.
+func (sp SweetPlacement) Validate() error {
+	switch sp.Strategy {
+	case "", PlacementUniform, PlacementClustered, PlacementSymmetric:
+	default:
+		return fmt.Errorf("unknown sweet placement strategy %q", sp.Strategy)
+	}
+	if sp.MinSpacing < 0 || sp.Regions < 0 || sp.Clusters < 0 {
+		return fmt.Errorf("sweet placement values must be positive")
+	}
+	return nil
+}
+
+// SetSweetPlacement changes how sweets are placed, from the next round on.
+// There can be at most as many regions per side as cells on the shortest side of the board.
+func (g *Game) SetSweetPlacement(sp SweetPlacement) error {
+	if err := sp.Validate(); err != nil {
+		return err
+	}
+	if sp.Regions > min(g.W, g.H) {
+		return fmt.Errorf("sweet placement: at most %d regions on a %dx%d board", min(g.W, g.H), g.W, g.H)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.placement = sp
+	g.record(RecordEntry{Kind: "placement", Placement: &sp})
+	return nil
+}
+
+// SweetPlacement returns how sweets are placed.
+func (g *Game) SweetPlacement() SweetPlacement {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.placement
+}
+
+// SetWalls turns the given cells into walls (map layout): players cannot enter them,
+// nobody spawns on them and sweets already there are removed.
+// A wall cannot go where a player stands: the layout is then refused as a whole.
+func (g *Game) SetWalls(walls []Pos) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, w := range walls {
+		if p := g.grid.playerAt(w.X, w.Y); p != nil {
+			return fmt.Errorf("wall on %d,%d: player %s stands there", w.X, w.Y, p.ID)
+		}
+	}
+	for _, w := range walls {
+		i := g.grid.cell(w.X, w.Y)
+		if i < 0 {
+			continue
+		}
+		g.grid.setWall(i)
+		for _, s := range append([]*Sweet(nil), g.grid.sweets[i]...) {
+			g.grid.removeSweet(s)
+			delete(g.sweets, s.ID)
+		}
+	}
+	g.record(RecordEntry{Kind: "walls", Points: walls})
+	return nil
+}
+
+// Walls returns the wall cells.
+func (g *Game) Walls() []Pos {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.wallsLocked()
+}
+
+func (g *Game) wallsLocked() []Pos {
+	walls := make([]Pos, 0)
+	for i, w := range g.grid.walls {
+		if w {
+			walls = append(walls, Pos{i % g.W, i / g.W})
+		}
+	}
+	return walls
+}
+
+// placeSweetsLocked places n new sweets (ids s1..sn) following g.placement.
+// When the board is too crowded for the requested spacing, the spacing is relaxed
+// rather than placing fewer sweets; it stops early only if no valid cell is left.
+// Caller must hold g.mu.
+func (g *Game) placeSweetsLocked(n int) {
+	pl := g.placement
+	spacing := pl.MinSpacing
+
+	// regions visited in a shuffled round robin so that each one gets its share
+	regions := clamp(pl.Regions, 1, min(g.W, g.H)) // recordings may predate the bound of SetSweetPlacement
+	order := g.rand.Perm(regions * regions)
+
+	// cluster centres, spread over the regions
+	var centres []Pos
+	if pl.Strategy == PlacementClustered {
+		k := pl.Clusters
+		if k == 0 {
+			k = 3
+		}
+		for c := 0; c < k; c++ {
+			centres = append(centres, g.randomInRegion(order[c%len(order)], regions))
+		}
+	}
+	radius := max(1, min(g.W, g.H)/5)
+
+	placed := 0
+	place := func(c Pos) {
+		placed++
+		g.placeSweetLocked(&Sweet{ID: fmt.Sprintf("s%d", placed), X: c.X, Y: c.Y})
+	}
+	for placed < n {
+		ok := false
+		for a := 0; a < placementAttempts && !ok; a++ {
+			var c Pos
+			switch pl.Strategy {
+			case PlacementClustered:
+				centre := centres[placed%len(centres)]
+				c = Pos{clamp(centre.X+g.rand.Intn(2*radius+1)-radius, 0, g.W-1), clamp(centre.Y+g.rand.Intn(2*radius+1)-radius, 0, g.H-1)}
+			default:
+				c = g.randomInRegion(order[placed%len(order)], regions)
+			}
+			if !g.canPlaceSweetLocked(c, spacing) {
+				continue
+			}
+			if pl.Strategy == PlacementSymmetric {
+				// the mirror must be free too; the centre cell is its own mirror and
+				// only used for the last sweet of an odd count to keep pairs complete
+				m := Pos{g.W - 1 - c.X, g.H - 1 - c.Y}
+				if m == c && n-placed > 1 {
+					continue
+				}
+				if m != c && (n-placed < 2 || !g.mirrorFreeLocked(c, m, spacing)) {
+					if n-placed >= 2 {
+						continue
+					}
+				}
+				ok = true
+				place(c)
+				if m != c && placed < n {
+					place(m)
+				}
+				continue
+			}
+			ok = true
+			place(c)
+		}
+		if ok {
+			continue
+		}
+		// random draws failed: scan the board (bounded by its size) before giving up on the spacing
+		if pl.Strategy == PlacementSymmetric && n-placed >= 2 {
+			if c, found := g.scanSweetPairLocked(spacing); found {
+				place(c)
+				place(Pos{g.W - 1 - c.X, g.H - 1 - c.Y})
+				continue
+			}
+		} else if c, found := g.scanSweetCellLocked(spacing); found {
+			place(c)
+			continue
+		}
+		if spacing == 0 {
+			return // board full
+		}
+		spacing-- // too crowded for the requested spacing
+	}
+}
+
+// randomInRegion returns a random cell of region r of a regions x regions split.
+func (g *Game) randomInRegion(r, regions int) Pos {
+	rx, ry := r%regions, r/regions
+	x0, x1 := rx*g.W/regions, (rx+1)*g.W/regions
+	y0, y1 := ry*g.H/regions, (ry+1)*g.H/regions
+	if x1 <= x0 || y1 <= y0 {
+		// more regions than cells, use the whole board
+		return Pos{g.rand.Intn(g.W), g.rand.Intn(g.H)}
+	}
+	return Pos{x0 + g.rand.Intn(x1-x0), y0 + g.rand.Intn(y1-y0)}
+}
+
+// canPlaceSweetLocked reports whether a sweet may go on c. Caller must hold g.mu.
+func (g *Game) canPlaceSweetLocked(c Pos, spacing int) bool {
+	if g.grid.wall(c.X, c.Y) || g.grid.playerAt(c.X, c.Y) != nil || g.grid.sweetAt(c.X, c.Y) != nil {
+		return false
+	}
+	return spacing <= 1 || g.grid.nearestSweet(c.X, c.Y, spacing-1) >= spacing
+}
+
+// scanSweetCellLocked returns a valid cell for a sweet, looking at every cell from a random start.
+func (g *Game) scanSweetCellLocked(spacing int) (Pos, bool) {
+	n := g.W * g.H
+	start := g.rand.Intn(n)
+	for k := 0; k < n; k++ {
+		i := (start + k) % n
+		c := Pos{i % g.W, i / g.W}
+		if g.canPlaceSweetLocked(c, spacing) {
+			return c, true
+		}
+	}
+	return Pos{}, false
+}
+
+// scanSweetPairLocked is scanSweetCellLocked for the symmetric placement: it returns a
+// cell whose mirror (another cell) is valid too, both being placed together.
+func (g *Game) scanSweetPairLocked(spacing int) (Pos, bool) {
+	n := g.W * g.H
+	start := g.rand.Intn(n)
+	for k := 0; k < n; k++ {
+		i := (start + k) % n
+		c := Pos{i % g.W, i / g.W}
+		m := Pos{g.W - 1 - c.X, g.H - 1 - c.Y}
+		if m != c && g.canPlaceSweetLocked(c, spacing) && g.mirrorFreeLocked(c, m, spacing) {
+			return c, true
+		}
+	}
+	return Pos{}, false
+}
+
+// mirrorFreeLocked reports whether m, the mirror of c, may hold a sweet along with c.
+func (g *Game) mirrorFreeLocked(c, m Pos, spacing int) bool {
+	return g.canPlaceSweetLocked(m, spacing) && (spacing <= 1 || max(abs(m.X-c.X), abs(m.Y-c.Y)) >= spacing)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
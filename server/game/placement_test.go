@@ -0,0 +1,191 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// sweetCells returns the cells holding a sweet and fails on stacked sweets.
+func sweetCells(t *testing.T, g *Game) map[Pos]bool {
+	t.Helper()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	cells := make(map[Pos]bool)
+	for _, s := range g.sweets {
+		c := Pos{s.X, s.Y}
+		if cells[c] {
+			t.Fatalf("two sweets on %v", c)
+		}
+		cells[c] = true
+	}
+	return cells
+}
+
+func TestSweetsNeverStackedOrUnderPlayers(t *testing.T) {
+	g := NewGame(5, 5, 0)
+	players := make(map[Pos]bool)
+	for i := 0; i < 5; i++ {
+		p := g.AddPlayer("A")
+		players[Pos{p.X, p.Y}] = true
+	}
+	// 20 sweets for exactly 20 free cells
+	g.Restart()
+	cells := sweetCells(t, g)
+	if len(cells) != 20 {
+		t.Fatalf("expected 20 sweets, got %d", len(cells))
+	}
+	for c := range cells {
+		if players[c] {
+			t.Fatalf("sweet placed under a player at %v", c)
+		}
+	}
+	checkGrid(t, g)
+}
+
+func TestWallsBlockSweetsAndMoves(t *testing.T) {
+	g := NewGame(5, 5, 0)
+	var walls []Pos
+	for y := 0; y < 5; y++ {
+		walls = append(walls, Pos{2, y})
+	}
+	if err := g.SetWalls(walls); err != nil {
+		t.Fatal(err)
+	}
+	g.Restart()
+	for c := range sweetCells(t, g) {
+		if c.X == 2 {
+			t.Fatalf("sweet placed on a wall at %v", c)
+		}
+	}
+	p := g.AddPlayer("A")
+	if p.X == 2 {
+		t.Fatalf("player spawned on a wall at %d,%d", p.X, p.Y)
+	}
+	g.ClearSweets()
+	g.SetPlayerPosition(p.ID, 1, 0)
+	g.PushCommand(Command{PlayerID: p.ID, Type: "move", Dir: "right"})
+	g.applyCommands()
+	if pl := g.GetPlayer(p.ID); pl.X != 1 {
+		t.Fatalf("player walked through a wall: %+v", pl)
+	}
+}
+
+func TestSweetMinSpacing(t *testing.T) {
+	g := NewGame(10, 10, 0)
+	g.rand = rand.New(rand.NewSource(1)) // greedy placement could jam with an unlucky draw
+	if err := g.SetSweetPlacement(SweetPlacement{MinSpacing: 3}); err != nil {
+		t.Fatal(err)
+	}
+	g.mu.Lock()
+	g.placeSweetsLocked(8)
+	g.mu.Unlock()
+	cells := sweetCells(t, g)
+	if len(cells) != 8 {
+		t.Fatalf("expected 8 sweets, got %d", len(cells))
+	}
+	for a := range cells {
+		for b := range cells {
+			if a != b && max(abs(a.X-b.X), abs(a.Y-b.Y)) < 3 {
+				t.Fatalf("sweets %v and %v closer than 3", a, b)
+			}
+		}
+	}
+}
+
+func TestSweetSpacingRelaxedWhenCrowded(t *testing.T) {
+	// 20 sweets cannot be 5 cells apart on a 10x10 board: all of them must still be placed
+	g := NewGame(10, 10, 0)
+	g.SetSweetPlacement(SweetPlacement{MinSpacing: 5})
+	g.Restart()
+	if n := len(sweetCells(t, g)); n != 20 {
+		t.Fatalf("expected 20 sweets, got %d", n)
+	}
+}
+
+func TestSweetRegionBalance(t *testing.T) {
+	g := NewGame(10, 10, 0)
+	g.SetSweetPlacement(SweetPlacement{Regions: 2})
+	g.Restart()
+	count := make(map[int]int)
+	for c := range sweetCells(t, g) {
+		count[(c.Y/5)*2+c.X/5]++
+	}
+	for r := 0; r < 4; r++ {
+		if count[r] != 5 {
+			t.Fatalf("expected 5 sweets per quadrant, got %v", count)
+		}
+	}
+}
+
+func TestSweetSymmetricPlacement(t *testing.T) {
+	g := NewGame(9, 7, 0)
+	g.SetSweetPlacement(SweetPlacement{Strategy: PlacementSymmetric})
+	g.Restart()
+	cells := sweetCells(t, g)
+	if len(cells) != 20 {
+		t.Fatalf("expected 20 sweets, got %d", len(cells))
+	}
+	for c := range cells {
+		if !cells[Pos{g.W - 1 - c.X, g.H - 1 - c.Y}] {
+			t.Fatalf("sweet %v has no mirror", c)
+		}
+	}
+}
+
+func TestWallsRefusedUnderPlayers(t *testing.T) {
+	g := NewGame(5, 5, 0)
+	p := g.AddPlayer("A")
+	if err := g.SetWalls([]Pos{{(p.X + 1) % 5, p.Y}, {p.X, p.Y}}); err == nil {
+		t.Fatalf("expected error for a wall under a player")
+	}
+	if walls := g.Walls(); len(walls) != 0 {
+		t.Fatalf("refused layout partly applied: %v", walls)
+	}
+}
+
+func TestSweetSymmetricPlacementCrowded(t *testing.T) {
+	// a spacing of 4 leaves few valid pairs: the draws fail and the board is scanned
+	for seed := int64(0); seed < 20; seed++ {
+		g := NewGame(9, 7, 0)
+		g.rand = rand.New(rand.NewSource(seed))
+		g.SetSweetPlacement(SweetPlacement{Strategy: PlacementSymmetric, MinSpacing: 4})
+		g.mu.Lock()
+		g.placeSweetsLocked(20)
+		g.mu.Unlock()
+		cells := sweetCells(t, g)
+		if len(cells) != 20 {
+			t.Fatalf("seed %d: expected 20 sweets, got %d", seed, len(cells))
+		}
+		for c := range cells {
+			if !cells[Pos{g.W - 1 - c.X, g.H - 1 - c.Y}] {
+				t.Fatalf("seed %d: sweet %v has no mirror", seed, c)
+			}
+		}
+	}
+}
+
+func TestSweetClusteredPlacement(t *testing.T) {
+	g := NewGame(20, 20, 0)
+	g.SetSweetPlacement(SweetPlacement{Strategy: PlacementClustered, Clusters: 2})
+	g.Restart()
+	if n := len(sweetCells(t, g)); n != 20 {
+		t.Fatalf("expected 20 sweets, got %d", n)
+	}
+	checkGrid(t, g)
+}
+
+func TestSweetPlacementValidate(t *testing.T) {
+	g := NewGame(5, 5, 0)
+	if err := g.SetSweetPlacement(SweetPlacement{Strategy: "spiral"}); err == nil {
+		t.Fatalf("expected error for unknown strategy")
+	}
+	if err := g.SetSweetPlacement(SweetPlacement{MinSpacing: -1}); err == nil {
+		t.Fatalf("expected error for negative spacing")
+	}
+	if err := g.SetSweetPlacement(SweetPlacement{Regions: 6}); err == nil {
+		t.Fatalf("expected error for more regions than cells per side")
+	}
+	if err := g.SetSweetPlacement(SweetPlacement{Regions: 5}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,125 @@
+package game
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// RecordEntry is one line of a match log.
+// Entries are written in the order the game state was modified (under g.mu),
+// so replaying them in order through a Game gives the same result.
+type RecordEntry struct {
+	Kind string `json:"kind"` // "start","join","leave","tick","restart","game_over","set_sweet","clear_sweets","set_position","spawn_points","walls","placement","endless","spawn_sweets","rules"
+	Tick int64  `json:"tick"`
+	// "start" only: seed of the random source and the state at the moment the recording began
+	Seed    int64     `json:"seed,omitempty"`
+	W       int       `json:"w,omitempty"`
+	H       int       `json:"h,omitempty"`
+	TPS     int       `json:"tps,omitempty"`
+	Players []*Player `json:"players,omitempty"`
+	Sweets  []*Sweet  `json:"sweets,omitempty"`
+	NextID  int       `json:"next_id,omitempty"`
+	// "start", "spawn_points" and "walls"
+	Points []Pos `json:"points,omitempty"`
+	Walls  []Pos `json:"walls,omitempty"`
+	// "start" and "placement"
+	Placement *SweetPlacement `json:"placement,omitempty"`
+	// "start" and "rules"
+	Rules *Rules `json:"rules,omitempty"`
+	// "start" and "endless"
+	Endless    *EndlessMode `json:"endless,omitempty"`
+	RoundStart int64        `json:"round_start,omitempty"`
+	Respawns   []respawn    `json:"respawns,omitempty"`
+	// join / leave / set_sweet / set_position
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+	X    int    `json:"x,omitempty"`
+	Y    int    `json:"y,omitempty"`
+	// spawn_sweets: number of sweets requested
+	Count int `json:"count,omitempty"`
+	// tick: commands applied during the tick, in processing order
+	Commands []Command `json:"commands,omitempty"`
+	// game_over: final scores of the round
+	Scores []Score `json:"scores,omitempty"`
+}
+
+// Score is the result of a player at the end of a round.
+type Score struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Score int    `json:"score"`
+}
+
+// recorder writes entries as JSON lines.
+type recorder struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// StartRecording begins writing the match log to w.
+// The random source is reseeded so that the log is enough to replay the match.
+func (g *Game) StartRecording(w io.Writer) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	bw := bufio.NewWriter(w)
+	g.recorder = &recorder{w: bw, enc: json.NewEncoder(bw)}
+	seed := time.Now().UnixNano()
+	g.rand = rand.New(rand.NewSource(seed))
+	players := make([]*Player, 0, len(g.players))
+	for _, p := range g.players {
+		cp := *p
+		players = append(players, &cp)
+	}
+	sweets := make([]*Sweet, 0, len(g.sweets))
+	for _, s := range g.sweets {
+		cp := *s
+		sweets = append(sweets, &cp)
+	}
+	g.record(RecordEntry{Kind: "start", Seed: seed, W: g.W, H: g.H, TPS: g.tps, Players: players, Sweets: sweets, NextID: g.nextID, Points: g.spawnPoints,
+		Walls: g.wallsLocked(), Placement: &g.placement, Rules: &g.rules, Endless: g.endless, RoundStart: g.roundStart, Respawns: g.respawns})
+	return bw.Flush()
+}
+
+// StopRecording flushes and detaches the match log.
+func (g *Game) StopRecording() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.recorder == nil {
+		return nil
+	}
+	err := g.recorder.w.Flush()
+	g.recorder = nil
+	return err
+}
+
+// record appends an entry to the match log. Caller must hold g.mu.
+func (g *Game) record(e RecordEntry) {
+	if g.recorder == nil {
+		return
+	}
+	e.Tick = g.tick
+	// write errors are checked by StartRecording/StopRecording, the game must not stop for a full disk
+	_ = g.recorder.enc.Encode(e)
+	_ = g.recorder.w.Flush()
+}
+
+// Scores returns the current scores sorted by player ID.
+func (g *Game) Scores() []Score {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.scoresLocked()
+}
+
+// scoresLocked returns the scores sorted by player ID. Caller must hold g.mu.
+func (g *Game) scoresLocked() []Score {
+	scores := make([]Score, 0, len(g.players))
+	for _, p := range g.players {
+		scores = append(scores, Score{ID: p.ID, Name: p.Name, Score: p.Score})
+	}
+	sort.Slice(scores, func(i, j int) bool { return scores[i].ID < scores[j].ID })
+	return scores
+}
@@ -0,0 +1,165 @@
+package game
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+)
+
+// Replay re-simulates a recorded match through a Game (see StartRecording).
+type Replay struct {
+	Game    *Game
+	TPS     int // tick rate of the recorded match
+	Rounds  int // number of rounds whose scores were verified
+	entries []RecordEntry
+	onTick  func(tick int64)
+}
+
+// LoadReplay reads a match log and prepares a game in the state the recording started from.
+func LoadReplay(r io.Reader) (*Replay, error) {
+	var entries []RecordEntry
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024) // tick lines can hold up to 1024 commands
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var e RecordEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("line %d: %w", len(entries)+1, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 || entries[0].Kind != "start" {
+		return nil, fmt.Errorf("match log must begin with a start entry")
+	}
+
+	start := entries[0]
+	g := NewGame(start.W, start.H, 0)
+	g.rand = rand.New(rand.NewSource(start.Seed))
+	g.tick = start.Tick
+	g.tps = start.TPS
+	g.nextID = start.NextID
+	g.spawnPoints = start.Points
+	for _, p := range start.Players {
+		cp := *p
+		g.players[cp.ID] = &cp
+	}
+	for _, s := range start.Sweets {
+		cp := *s
+		g.sweets[cp.ID] = &cp
+	}
+	g.endless = start.Endless
+	g.roundStart = start.RoundStart
+	g.respawns = start.Respawns
+	if start.Placement != nil {
+		g.placement = *start.Placement
+	}
+	if start.Rules != nil {
+		g.rules = *start.Rules // older logs: the default rules
+	}
+	for _, w := range start.Walls {
+		if i := g.grid.cell(w.X, w.Y); i >= 0 {
+			g.grid.setWall(i)
+		}
+	}
+	g.reindexLocked()
+	return &Replay{Game: g, TPS: start.TPS, entries: entries[1:]}, nil
+}
+
+// Run applies every entry of the log in order, broadcasting the state at each tick like the live loop.
+// onTick is called after each tick broadcast (nil to run as fast as possible).
+// It returns an error as soon as the replayed game diverges from the recording.
+func (rp *Replay) Run(onTick func(tick int64)) error {
+	rp.onTick = onTick
+	g := rp.Game
+	for i, e := range rp.entries {
+		if e.Kind == "tick" {
+			rp.advance(e.Tick - 1)
+			rp.runTick(e.Commands)
+			continue
+		}
+		rp.advance(e.Tick)
+		switch e.Kind {
+		case "join":
+			p := g.AddPlayer(e.Name)
+			if p == nil || p.ID != e.ID {
+				return fmt.Errorf("entry %d: join of %q diverged (expected id %s)", i+1, e.Name, e.ID)
+			}
+		case "leave":
+			g.RemovePlayer(e.ID)
+		case "restart":
+			g.Restart()
+		case "set_sweet":
+			g.SetSweet(e.ID, e.X, e.Y)
+		case "clear_sweets":
+			g.ClearSweets()
+		case "spawn_sweets":
+			g.SpawnSweets(e.Count)
+		case "set_position":
+			g.SetPlayerPosition(e.ID, e.X, e.Y)
+		case "spawn_points":
+			g.SetSpawnPoints(e.Points)
+		case "walls":
+			g.SetWalls(e.Points)
+		case "placement":
+			if e.Placement != nil {
+				g.SetSweetPlacement(*e.Placement)
+			}
+		case "endless":
+			g.SetEndless(e.Endless)
+		case "rules":
+			if e.Rules != nil {
+				g.SetRules(*e.Rules)
+			}
+		case "game_over":
+			got := g.Scores()
+			if !sameScores(got, e.Scores) {
+				return fmt.Errorf("tick %d: scores diverged: recorded %v, replayed %v", e.Tick, e.Scores, got)
+			}
+			g.gameOver()
+			rp.Rounds++
+		default:
+			return fmt.Errorf("entry %d: unknown kind %q", i+1, e.Kind)
+		}
+	}
+	return nil
+}
+
+// advance runs idle ticks (no commands recorded) up to tick.
+func (rp *Replay) advance(tick int64) {
+	for rp.Game.tick < tick {
+		rp.runTick(nil)
+	}
+}
+
+// runTick replays one tick like Game.step does.
+func (rp *Replay) runTick(cmds []Command) {
+	g := rp.Game
+	g.mu.Lock()
+	g.tick++
+	g.applyCommandsLocked(cmds)
+	g.respawnLocked()
+	g.mu.Unlock()
+	g.broadcastState()
+	if rp.onTick != nil {
+		rp.onTick(g.tick)
+	}
+}
+
+func sameScores(a, b []Score) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
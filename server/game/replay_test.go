@@ -0,0 +1,84 @@
+package game
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// recordMatch plays a small deterministic match with a recorder attached.
+func recordMatch(t *testing.T) *bytes.Buffer {
+	var log bytes.Buffer
+	g := NewGame(5, 5, 3)
+	if err := g.StartRecording(&log); err != nil {
+		t.Fatalf("start recording: %v", err)
+	}
+	// each player is placed before the next one spawns, which avoids the taken cells
+	a := g.AddPlayer("A")
+	g.SetPlayerPosition(a.ID, 0, 0)
+	b := g.AddPlayer("B")
+	g.SetPlayerPosition(b.ID, 4, 4)
+	g.ClearSweets()
+	g.SetSweet("s1", 1, 0)
+	g.SetSweet("s2", 3, 4)
+	g.SetSweet("s3", 2, 0)
+
+	g.PushCommand(Command{PlayerID: a.ID, Type: "move", Dir: "right"})
+	g.PushCommand(Command{PlayerID: b.ID, Type: "move", Dir: "left"})
+	g.step()
+	g.step() // idle tick
+	g.PushCommand(Command{PlayerID: a.ID, Type: "move", Dir: "right"})
+	if !g.step() {
+		t.Fatalf("expected round to be over")
+	}
+	g.gameOver()
+	g.Restart()
+	g.RemovePlayer(b.ID)
+	if err := g.StopRecording(); err != nil {
+		t.Fatalf("stop recording: %v", err)
+	}
+	return &log
+}
+
+func TestReplayMatchesRecording(t *testing.T) {
+	log := recordMatch(t)
+	rp, err := LoadReplay(bytes.NewReader(log.Bytes()))
+	if err != nil {
+		t.Fatalf("load replay: %v", err)
+	}
+	ticks := 0
+	if err := rp.Run(func(int64) { ticks++ }); err != nil {
+		t.Fatalf("replay diverged: %v", err)
+	}
+	if rp.Rounds != 1 {
+		t.Fatalf("expected 1 verified round, got %d", rp.Rounds)
+	}
+	if ticks != 3 {
+		t.Fatalf("expected 3 replayed ticks, got %d", ticks)
+	}
+	if rp.Game.SweetsCount() != 20 {
+		t.Fatalf("expected restarted board with 20 sweets, got %d", rp.Game.SweetsCount())
+	}
+	if len(rp.Game.Scores()) != 1 {
+		t.Fatalf("expected B to have left, got %v", rp.Game.Scores())
+	}
+}
+
+func TestReplayDetectsDivergence(t *testing.T) {
+	log := recordMatch(t)
+	// tamper with the recorded scores
+	tampered := strings.Replace(log.String(), `"score":2`, `"score":3`, 1)
+	rp, err := LoadReplay(strings.NewReader(tampered))
+	if err != nil {
+		t.Fatalf("load replay: %v", err)
+	}
+	if err := rp.Run(nil); err == nil {
+		t.Fatalf("expected divergence error")
+	}
+}
+
+func TestLoadReplayRequiresStart(t *testing.T) {
+	if _, err := LoadReplay(strings.NewReader(`{"kind":"join","tick":1,"id":"p-1","name":"A"}`)); err == nil {
+		t.Fatalf("expected error for log without start entry")
+	}
+}
@@ -0,0 +1,72 @@
+package game
+
+import (
+	"fmt"
+	"time"
+)
+
+// Rules are the settings of a round, set per game (see the config package).
+type Rules struct {
+	Sweets       int           `json:"sweets"`       // sweets placed at the start of each round
+	MaxMoves     int           `json:"max_moves"`    // moves a player may make per tick
+	Intermission time.Duration `json:"intermission"` // pause between two rounds
+}
+
+// DefaultRules are the rules of a new game.
+var DefaultRules = Rules{Sweets: 20, MaxMoves: 2, Intermission: 5 * time.Second}
+
+// BroadcastBuffer is the size of the state and event channels of the games created next:
+// messages are dropped when the hub falls this far behind.
+var BroadcastBuffer = 10
+
+// Validate checks the rules.
+func (r Rules) Validate() error {
+	switch {
+	case r.Sweets < 0:
+		return fmt.Errorf("sweets must not be negative")
+	case r.MaxMoves < 1:
+		return fmt.Errorf("max moves per tick must be at least 1")
+	case r.Intermission < 0:
+		return fmt.Errorf("intermission must not be negative")
+	}
+	return nil
+}
+
+// Rules returns the rules of the game.
+func (g *Game) Rules() Rules {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.rules
+}
+
+// SetRules changes the rules: the move limit applies from the next tick, the number of
+// sweets from the next round. Rules set by SetNextRules are dropped.
+func (g *Game) SetRules(r Rules) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.rules, g.nextRules = r, nil
+	g.record(RecordEntry{Kind: "rules", Rules: &r})
+	return nil
+}
+
+// SetNextRules changes the rules from the next round on, the current round keeps its own.
+func (g *Game) SetNextRules(r Rules) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.nextRules = &r
+	return nil
+}
+
+// SetTPS sets the tick rate of a game whose loop is not started yet (Start sets it too),
+// so that Ticks and the match log know it.
+func (g *Game) SetTPS(tps int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.tps = tps
+}
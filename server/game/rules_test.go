@@ -0,0 +1,60 @@
+package game
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	var log bytes.Buffer
+	g := NewGame(6, 1, 0)
+	if err := g.StartRecording(&log); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetRules(Rules{Sweets: 2, MaxMoves: 0}); err == nil {
+		t.Fatal("a move limit of 0 was accepted")
+	}
+	if g.Rules() != DefaultRules {
+		t.Fatalf("invalid rules were applied: %+v", g.Rules())
+	}
+	if err := g.SetRules(Rules{Sweets: 2, MaxMoves: 3}); err != nil {
+		t.Fatal(err)
+	}
+	p := g.AddPlayer("A")
+	g.SetPlayerPosition(p.ID, 0, 0)
+	for i := 0; i < 5; i++ {
+		g.PushCommand(Command{PlayerID: p.ID, Type: "move", Dir: "right"})
+	}
+	g.step()
+	if x := g.GetPlayer(p.ID).X; x != 3 {
+		t.Fatalf("expected 3 moves in the tick, got to x=%d", x)
+	}
+	g.Restart()
+	if n := g.SweetsCount(); n != 2 {
+		t.Fatalf("expected 2 sweets in the new round, got %d", n)
+	}
+
+	// rules set for the next round wait for the restart
+	if err := g.SetNextRules(Rules{Sweets: 4, MaxMoves: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if g.Rules().MaxMoves != 3 {
+		t.Fatal("next round rules applied to the current round")
+	}
+	g.Restart()
+	if n := g.SweetsCount(); n != 4 || g.Rules().MaxMoves != 1 {
+		t.Fatalf("next round rules not applied: %d sweets, %+v", n, g.Rules())
+	}
+	g.StopRecording()
+
+	rp, err := LoadReplay(bytes.NewReader(log.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rp.Run(nil); err != nil {
+		t.Fatalf("replay diverged: %v", err)
+	}
+	if rp.Game.Rules() != g.Rules() || rp.Game.SweetsCount() != 4 || rp.Game.GetPlayer(p.ID).X != 3 {
+		t.Fatalf("replayed rules %+v, expected %+v", rp.Game.Rules(), g.Rules())
+	}
+}
@@ -0,0 +1,67 @@
+package game
+
+// Pos is a cell of the board.
+type Pos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+const (
+	spawnCandidates = 8 // free cells sampled per spawn, the one farthest from other players wins
+	spawnRadius     = 4 // players further than this are considered far enough
+)
+
+// SetSpawnPoints restricts where players appear (map-defined spawn points).
+// Out of board points and walls are ignored, as the points walls cover later.
+// An empty list means anywhere on the board.
+// When every spawn point is occupied, players fall back to any free cell.
+func (g *Game) SetSpawnPoints(points []Pos) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	valid := make([]Pos, 0, len(points))
+	for _, sp := range points {
+		if !g.grid.wall(sp.X, sp.Y) {
+			valid = append(valid, sp)
+		}
+	}
+	g.spawnPoints = valid
+	g.record(RecordEntry{Kind: "spawn_points", Points: g.spawnPoints})
+}
+
+// spawnLocked picks the cell of a new player in bounded time:
+// the free spawn point farthest from other players if the map defines some,
+// otherwise the best of a few cells drawn uniformly among the free ones.
+// It returns false if the board is full. Caller must hold g.mu.
+func (g *Game) spawnLocked() (int, int, bool) {
+	if len(g.spawnPoints) > 0 {
+		best, bestDist := -1, -1
+		// start at a random point so that equally good points are picked in turn
+		offset := g.rand.Intn(len(g.spawnPoints))
+		for k := range g.spawnPoints {
+			i := (offset + k) % len(g.spawnPoints)
+			sp := g.spawnPoints[i]
+			if g.grid.wall(sp.X, sp.Y) || g.grid.playerAt(sp.X, sp.Y) != nil {
+				continue // SetWalls may have covered it
+			}
+			if d := g.grid.nearestPlayer(sp.X, sp.Y, spawnRadius); d > bestDist {
+				best, bestDist = i, d
+			}
+		}
+		if best >= 0 {
+			return g.spawnPoints[best].X, g.spawnPoints[best].Y, true
+		}
+	}
+
+	free := g.grid.free
+	if len(free) == 0 {
+		return 0, 0, false // no space left
+	}
+	best, bestDist := -1, -1
+	for k := 0; k < spawnCandidates && bestDist <= spawnRadius; k++ {
+		c := free[g.rand.Intn(len(free))]
+		if d := g.grid.nearestPlayer(c%g.W, c/g.W, spawnRadius); d > bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best % g.W, best / g.W, true
+}
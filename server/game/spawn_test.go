@@ -0,0 +1,110 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAddPlayerFillsBoard(t *testing.T) {
+	g := NewGame(4, 3, 0)
+	seen := make(map[Pos]bool)
+	for i := 0; i < g.W*g.H; i++ {
+		p := g.AddPlayer(fmt.Sprintf("P%d", i))
+		if p == nil {
+			t.Fatalf("board full after %d players", i)
+		}
+		pos := Pos{p.X, p.Y}
+		if seen[pos] {
+			t.Fatalf("two players spawned on %v", pos)
+		}
+		seen[pos] = true
+	}
+	if p := g.AddPlayer("extra"); p != nil {
+		t.Fatalf("expected nil on a full board, got %+v", p)
+	}
+	checkGrid(t, g)
+}
+
+func TestPlayerIDsNotReused(t *testing.T) {
+	g := NewGame(5, 5, 0)
+	a := g.AddPlayer("A")
+	b := g.AddPlayer("B")
+	g.RemovePlayer(a.ID)
+	c := g.AddPlayer("C")
+	if c.ID == b.ID || c.ID == a.ID {
+		t.Fatalf("id %s reused", c.ID)
+	}
+	if g.GetPlayer(b.ID) == nil {
+		t.Fatalf("player B was replaced")
+	}
+	checkGrid(t, g)
+}
+
+func TestSpawnPointsRespected(t *testing.T) {
+	g := NewGame(10, 10, 0)
+	points := []Pos{{0, 0}, {9, 9}, {42, 42}} // last one is out of the board
+	g.SetSpawnPoints(points)
+	a := g.AddPlayer("A")
+	b := g.AddPlayer("B")
+	for _, p := range []*Player{a, b} {
+		if !((p.X == 0 && p.Y == 0) || (p.X == 9 && p.Y == 9)) {
+			t.Fatalf("player %s spawned outside spawn points at %d,%d", p.ID, p.X, p.Y)
+		}
+	}
+	if a.X == b.X {
+		t.Fatalf("both players on the same spawn point")
+	}
+	// every spawn point taken: fall back to any free cell
+	if c := g.AddPlayer("C"); c == nil {
+		t.Fatalf("expected fallback spawn")
+	}
+}
+
+func TestSpawnAwayFromPlayers(t *testing.T) {
+	g := NewGame(10, 1, 0)
+	g.SetSpawnPoints([]Pos{{1, 0}, {8, 0}})
+	g.mu.Lock()
+	g.players = map[string]*Player{"p-9": {ID: "p-9", X: 0, Y: 0}}
+	g.nextID = 9
+	g.reindexLocked()
+	g.mu.Unlock()
+	for i := 0; i < 10; i++ {
+		p := g.AddPlayer("A")
+		if p.X != 8 {
+			t.Fatalf("expected spawn on the far point (8,0), got %d,%d", p.X, p.Y)
+		}
+		g.RemovePlayer(p.ID)
+	}
+}
+
+// BenchmarkAddPlayerCrowded adds and removes a player on a 100x100 board holding 9000 players.
+func BenchmarkAddPlayerCrowded(b *testing.B) {
+	g := NewGame(100, 100, 0)
+	for i := 0; i < 9000; i++ {
+		g.AddPlayer("bot")
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := g.AddPlayer("new")
+		g.RemovePlayer(p.ID)
+	}
+}
+
+func TestSpawnPointsSkipWalls(t *testing.T) {
+	g := NewGame(10, 10, 0)
+	g.SetWalls([]Pos{{0, 0}})
+	g.SetSpawnPoints([]Pos{{0, 0}, {5, 5}, {9, 9}}) // 0,0 is dropped
+	g.SetWalls([]Pos{{9, 9}})                       // covers a spawn point afterwards
+	for i := 0; i < 10; i++ {
+		p := g.AddPlayer("A")
+		if p.X != 5 || p.Y != 5 {
+			t.Fatalf("expected spawn on the only open point (5,5), got %d,%d", p.X, p.Y)
+		}
+		g.RemovePlayer(p.ID)
+	}
+	g.AddPlayer("A")
+	if p := g.AddPlayer("B"); g.grid.wall(p.X, p.Y) {
+		t.Fatalf("fallback spawn on a wall at %d,%d", p.X, p.Y)
+	}
+	checkGrid(t, g)
+}
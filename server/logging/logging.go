@@ -0,0 +1,50 @@
+// Package logging sets up the log/slog default logger from the command line flags,
+// and samples the messages logged too often to be useful one by one.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+// Setup makes slog log to w at level (debug, info, warn or error) in format (text or json).
+// The log package goes through it too.
+func Setup(w io.Writer, level, format string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("log level %q: expected debug, info, warn or error", level)
+	}
+	opts := &slog.HandlerOptions{Level: l}
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "", "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return fmt.Errorf("log format %q: expected text or json", format)
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// Sampler keeps the first of every N events, for messages too frequent to log them all
+// (e.g. moves at 20 per second per player). Safe for concurrent use.
+type Sampler struct {
+	every uint64
+	n     atomic.Uint64
+}
+
+// NewSampler keeps 1 event in every, 0 drops them all.
+func NewSampler(every int) *Sampler {
+	return &Sampler{every: uint64(max(every, 0))}
+}
+
+// Sample counts an event and reports whether to log it, with the number of events so far.
+func (s *Sampler) Sample() (bool, uint64) {
+	n := s.n.Add(1)
+	return s.every > 0 && (n-1)%s.every == 0, n
+}
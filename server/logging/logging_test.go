@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"testing"
+)
+
+func TestSetup(t *testing.T) {
+	out, flags := log.Writer(), log.Flags()
+	defer func() {
+		log.SetOutput(out)
+		log.SetFlags(flags)
+	}()
+	defer slog.SetDefault(slog.Default())
+	var buf bytes.Buffer
+	if err := Setup(&buf, "warn", "json"); err != nil {
+		t.Fatal(err)
+	}
+	slog.Info("hidden")
+	slog.Warn("shown", "room", "default")
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("expected one JSON line, got %q", buf.String())
+	}
+	if line["msg"] != "shown" || line["room"] != "default" {
+		t.Fatalf("unexpected line %v", line)
+	}
+	if Setup(&buf, "loud", "text") == nil || Setup(&buf, "info", "xml") == nil {
+		t.Fatal("bad level or format accepted")
+	}
+}
+
+func TestSampler(t *testing.T) {
+	s := NewSampler(3)
+	var kept []uint64
+	for i := 0; i < 7; i++ {
+		if ok, n := s.Sample(); ok {
+			kept = append(kept, n)
+		}
+	}
+	if len(kept) != 3 || kept[0] != 1 || kept[1] != 4 || kept[2] != 7 {
+		t.Fatalf("kept %v, expected [1 4 7]", kept)
+	}
+	if ok, _ := NewSampler(0).Sample(); ok {
+		t.Fatal("a zero sampler keeps nothing")
+	}
+}
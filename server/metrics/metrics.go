@@ -0,0 +1,301 @@
+// Package metrics is a minimal Prometheus text-format exposition: counters, gauges and
+// histograms, with at most one label. Enough for /metrics without external dependencies.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// metric is anything the registry can write.
+type metric interface {
+	write(w io.Writer)
+}
+
+// Registry holds the metrics exposed by a handler, in registration order.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+// Default is the registry served on /metrics.
+var Default = &Registry{}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	r.metrics = append(r.metrics, m)
+	r.mu.Unlock()
+}
+
+// Write writes every metric in the Prometheus text format.
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	list := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+	for _, m := range list {
+		m.write(w)
+	}
+}
+
+// Handler serves the registry.
+func (r *Registry) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	}
+}
+
+func header(w io.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// labelValue escapes a label value.
+func labelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+// Counter is a value that only goes up.
+type Counter struct {
+	v atomic.Uint64
+}
+
+func (c *Counter) Inc()          { c.v.Add(1) }
+func (c *Counter) Add(n uint64)  { c.v.Add(n) }
+func (c *Counter) Value() uint64 { return c.v.Load() }
+
+type counter struct {
+	Counter
+	name, help string
+}
+
+func (c *counter) write(w io.Writer) {
+	header(w, c.name, c.help, "counter")
+	fmt.Fprintf(w, "%s %d\n", c.name, c.Value())
+}
+
+// NewCounter registers a counter in the Default registry.
+func NewCounter(name, help string) *Counter {
+	c := &counter{name: name, help: help}
+	Default.register(c)
+	return &c.Counter
+}
+
+// CounterVec is a set of counters with one label.
+type CounterVec struct {
+	name, help, label string
+	mu                sync.Mutex
+	counters          map[string]*Counter
+}
+
+// NewCounterVec registers a counter vector in the Default registry.
+func NewCounterVec(name, help, label string) *CounterVec {
+	v := &CounterVec{name: name, help: help, label: label, counters: make(map[string]*Counter)}
+	Default.register(v)
+	return v
+}
+
+// With returns the counter of a label value.
+func (v *CounterVec) With(value string) *Counter {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	c, ok := v.counters[value]
+	if !ok {
+		c = &Counter{}
+		v.counters[value] = c
+	}
+	return c
+}
+
+func (v *CounterVec) write(w io.Writer) {
+	header(w, v.name, v.help, "counter")
+	v.mu.Lock()
+	values := make([]string, 0, len(v.counters))
+	for k := range v.counters {
+		values = append(values, k)
+	}
+	sort.Strings(values)
+	for _, k := range values {
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %d\n", v.name, v.label, labelValue(k), v.counters[k].Value())
+	}
+	v.mu.Unlock()
+}
+
+// Gauge is a value that goes up and down.
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+func (g *Gauge) Set(v float64)  { g.bits.Store(math.Float64bits(v)) }
+func (g *Gauge) Value() float64 { return math.Float64frombits(g.bits.Load()) }
+
+type gauge struct {
+	Gauge
+	name, help string
+}
+
+func (g *gauge) write(w io.Writer) {
+	header(w, g.name, g.help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.Value()))
+}
+
+// NewGauge registers a gauge in the Default registry.
+func NewGauge(name, help string) *Gauge {
+	g := &gauge{name: name, help: help}
+	Default.register(g)
+	return &g.Gauge
+}
+
+// gaugeFunc is a gauge computed when scraped, one sample per label value.
+type gaugeFunc struct {
+	name, help, label string
+	f                 func() map[string]float64
+}
+
+func (g *gaugeFunc) write(w io.Writer) {
+	header(w, g.name, g.help, "gauge")
+	values := g.f()
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if g.label == "" {
+			fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(values[k]))
+		} else {
+			fmt.Fprintf(w, "%s{%s=\"%s\"} %s\n", g.name, g.label, labelValue(k), formatFloat(values[k]))
+		}
+	}
+}
+
+// NewGaugeFunc registers a gauge whose value is computed by f at each scrape.
+func NewGaugeFunc(name, help string, f func() float64) {
+	Default.register(&gaugeFunc{name: name, help: help, f: func() map[string]float64 {
+		return map[string]float64{"": f()}
+	}})
+}
+
+// NewGaugeVecFunc registers a gauge with one label whose values are computed by f at each scrape.
+func NewGaugeVecFunc(name, help, label string, f func() map[string]float64) {
+	Default.register(&gaugeFunc{name: name, help: help, label: label, f: f})
+}
+
+// Histogram counts observations in cumulative buckets.
+type Histogram struct {
+	name, help, label string
+	value             string // label value, "" if none
+	buckets           []float64
+	mu                sync.Mutex
+	counts            []uint64 // per bucket, not cumulative
+	sum               float64
+	count             uint64
+}
+
+// NewHistogram registers a histogram with the given upper bounds (sorted) in the Default registry.
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	h := newHistogram(name, help, "", "", buckets)
+	Default.register(h)
+	return h
+}
+
+func newHistogram(name, help, label, value string, buckets []float64) *Histogram {
+	return &Histogram{name: name, help: help, label: label, value: value, buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+// Observe adds a value.
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.mu.Lock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.sum += v
+	h.count++
+	h.mu.Unlock()
+}
+
+func (h *Histogram) write(w io.Writer) {
+	header(w, h.name, h.help, "histogram")
+	h.samples(w)
+}
+
+func (h *Histogram) samples(w io.Writer) {
+	labels := ""
+	if h.label != "" {
+		labels = fmt.Sprintf("%s=\"%s\",", h.label, labelValue(h.value))
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var cum uint64
+	for i, b := range h.buckets {
+		cum += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{%sle=\"%s\"} %d\n", h.name, labels, formatFloat(b), cum)
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", h.name, labels, h.count)
+	if labels != "" {
+		labels = "{" + strings.TrimSuffix(labels, ",") + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %s\n%s_count%s %d\n", h.name, labels, formatFloat(h.sum), h.name, labels, h.count)
+}
+
+// HistogramVec is a set of histograms with one label.
+type HistogramVec struct {
+	name, help, label string
+	buckets           []float64
+	mu                sync.Mutex
+	hists             map[string]*Histogram
+}
+
+// NewHistogramVec registers a histogram vector in the Default registry.
+func NewHistogramVec(name, help, label string, buckets []float64) *HistogramVec {
+	v := &HistogramVec{name: name, help: help, label: label, buckets: buckets, hists: make(map[string]*Histogram)}
+	Default.register(v)
+	return v
+}
+
+// With returns the histogram of a label value.
+func (v *HistogramVec) With(value string) *Histogram {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	h, ok := v.hists[value]
+	if !ok {
+		h = newHistogram(v.name, v.help, v.label, value, v.buckets)
+		v.hists[value] = h
+	}
+	return h
+}
+
+func (v *HistogramVec) write(w io.Writer) {
+	header(w, v.name, v.help, "histogram")
+	v.mu.Lock()
+	values := make([]string, 0, len(v.hists))
+	for k := range v.hists {
+		values = append(values, k)
+	}
+	sort.Strings(values)
+	hists := make([]*Histogram, len(values))
+	for i, k := range values {
+		hists[i] = v.hists[k]
+	}
+	v.mu.Unlock()
+	for _, h := range hists {
+		h.samples(w)
+	}
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExposition(t *testing.T) {
+	Default = &Registry{}
+	c := NewCounter("test_total", "A counter.")
+	c.Add(3)
+	v := NewCounterVec("test_dropped_total", "A counter vector.", "reason")
+	v.With("full").Inc()
+	v.With(`a"b`).Inc()
+	NewGaugeVecFunc("test_players", "A gauge.", "room", func() map[string]float64 { return map[string]float64{"r1": 2} })
+	h := NewHistogram("test_seconds", "A histogram.", []float64{0.1, 1})
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(5)
+	hv := NewHistogramVec("test_bytes", "A histogram vector.", "direction", []float64{10})
+	hv.With("in").Observe(3)
+
+	var b bytes.Buffer
+	Default.Write(&b)
+	out := b.String()
+	for _, line := range []string{
+		"# TYPE test_total counter",
+		"test_total 3",
+		`test_dropped_total{reason="full"} 1`,
+		`test_dropped_total{reason="a\"b"} 1`,
+		`test_players{room="r1"} 2`,
+		"# TYPE test_seconds histogram",
+		`test_seconds_bucket{le="0.1"} 1`,
+		`test_seconds_bucket{le="1"} 2`,
+		`test_seconds_bucket{le="+Inf"} 3`,
+		"test_seconds_sum 5.55",
+		"test_seconds_count 3",
+		`test_bytes_bucket{direction="in",le="10"} 1`,
+		`test_bytes_count{direction="in"} 1`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, out)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package routes
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/anticheat"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+)
+
+// AdminToken protects the /api/admin endpoints ("Authorization: Bearer <token>").
+// Empty disables them.
+var AdminToken = ""
+
+// requireAdmin wraps an admin handler with the AdminToken check.
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		liveMu.RLock()
+		admin := AdminToken
+		liveMu.RUnlock()
+		if admin == "" {
+			writeError(w, http.StatusForbidden, "admin API disabled")
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(admin)) != 1 {
+			writeError(w, http.StatusUnauthorized, "invalid admin token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// CheatReport serves GET /api/admin/anticheat: suspicion scores and flags, most suspicious first.
+var CheatReport = requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"players": anticheat.Default.Report()})
+})
+
+// clients returns the clients of the room, players and spectators.
+func (r *Room) clients() []*Client {
+	r.hub.mu.Lock()
+	defer r.hub.mu.Unlock()
+	list := make([]*Client, 0, len(r.hub.clients))
+	for c := range r.hub.clients {
+		list = append(list, c)
+	}
+	return list
+}
+
+// disconnect kicks c: it gets a kicked event and a close frame, readPump then removes its player.
+func (c *Client) disconnect(room *Room, reason string) {
+	room.hub.remove(c) // no more broadcasts, before send is closed
+	c.kick(reason)
+	c.closeSend()
+}
+
+// roomReport describes a room for the admin API.
+func roomReport(room *Room) map[string]interface{} {
+	g := room.Game()
+	type conn struct{ remote, account string }
+	conns := make(map[string]conn)
+	spectators := 0
+	for _, c := range room.clients() {
+		_, id := c.current()
+		if id == "" {
+			spectators++
+			continue
+		}
+		conns[id] = conn{c.remote, c.account}
+	}
+	players := make([]map[string]interface{}, 0)
+	for _, p := range g.Players() {
+		players = append(players, map[string]interface{}{
+			"id": p.ID, "name": p.Name, "x": p.X, "y": p.Y, "score": p.Score,
+			"remote": conns[p.ID].remote, "account": conns[p.ID].account,
+		})
+	}
+	return map[string]interface{}{
+		"id":         room.ID,
+		"tick":       g.Tick(),
+		"paused":     g.Paused(),
+		"sleeping":   room.Sleeping(),
+		"sweets":     g.SweetsCount(),
+		"placement":  g.SweetPlacement(),
+		"walls":      g.Walls(),
+		"players":    players,
+		"spectators": spectators,
+	}
+}
+
+// adminRoom returns the room of the {id} path parameter, or answers 404.
+func adminRoom(w http.ResponseWriter, r *http.Request) *Room {
+	room := getRoom(r.PathValue("id"))
+	if room == nil {
+		writeError(w, http.StatusNotFound, "unknown room")
+	}
+	return room
+}
+
+// readBody decodes a small JSON body into v, an empty body leaves v as is.
+func readBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(v)
+	if err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return false
+	}
+	return true
+}
+
+// AdminRooms serves GET /api/admin/rooms: every room with its players.
+var AdminRooms = requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+	list := make([]map[string]interface{}, 0)
+	for _, room := range Rooms() {
+		list = append(list, roomReport(room))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"rooms": list})
+})
+
+// AdminRoom serves GET /api/admin/rooms/{id}.
+var AdminRoom = requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+	if room := adminRoom(w, r); room != nil {
+		writeJSON(w, http.StatusOK, roomReport(room))
+	}
+})
+
+// AdminRoomAction serves POST /api/admin/rooms/{id}/{action}: restart (new round now),
+// end (game_over at the next tick, new round after the usual delay), pause or resume.
+var AdminRoomAction = requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+	room := adminRoom(w, r)
+	if room == nil {
+		return
+	}
+	g := room.Game()
+	action := r.PathValue("action")
+	switch action {
+	case "restart":
+		g.Restart()
+	case "end":
+		g.EndRound()
+	case "pause":
+		g.Pause()
+	case "resume":
+		g.Resume()
+	default:
+		writeError(w, http.StatusNotFound, "unknown action, expected restart, end, pause or resume")
+		return
+	}
+	slog.Info("admin: room "+action, "room", room.ID)
+	writeJSON(w, http.StatusOK, roomReport(room))
+})
+
+// maxSpawn is the most sweets one request may add.
+const maxSpawn = 100
+
+// AdminSweets serves POST /api/admin/rooms/{id}/sweets: {"count":n} adds n sweets on
+// random free cells, {"x","y"} adds one on that cell. DELETE removes every sweet.
+var AdminSweets = requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+	room := adminRoom(w, r)
+	if room == nil {
+		return
+	}
+	g := room.Game()
+	if r.Method == http.MethodDelete {
+		g.ClearSweets()
+		slog.Info("admin: sweets cleared", "room", room.ID)
+		writeJSON(w, http.StatusOK, roomReport(room))
+		return
+	}
+	var body struct {
+		Count int  `json:"count"`
+		X     *int `json:"x"`
+		Y     *int `json:"y"`
+	}
+	if !readBody(w, r, &body) {
+		return
+	}
+	resp := map[string]interface{}{}
+	switch {
+	case body.X != nil && body.Y != nil:
+		id, err := g.PlaceSweet(*body.X, *body.Y)
+		if err == game.ErrOutOfBoard {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		resp["placed"] = []string{id}
+	case body.Count > 0 && body.Count <= maxSpawn:
+		resp["count"] = g.SpawnSweets(body.Count)
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("expected {\"count\":1..%d} or {\"x\",\"y\"}", maxSpawn))
+		return
+	}
+	slog.Info("admin: sweets added", "room", room.ID, "result", resp)
+	resp["room"] = roomReport(room)
+	writeJSON(w, http.StatusOK, resp)
+})
+
+// AdminPlacement serves POST /api/admin/rooms/{id}/placement: {"strategy","min_spacing",
+// "regions","clusters"} changes how the sweets are placed, from the next round on.
+// The walls come from the config only: the clients get them once, in join_ack.
+var AdminPlacement = requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+	room := adminRoom(w, r)
+	if room == nil {
+		return
+	}
+	var sp game.SweetPlacement
+	if !readBody(w, r, &sp) {
+		return
+	}
+	if err := room.Game().SetSweetPlacement(sp); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	slog.Info("admin: sweet placement changed", "room", room.ID, "placement", sp)
+	writeJSON(w, http.StatusOK, roomReport(room))
+})
+
+// AdminKick serves POST /api/admin/rooms/{id}/players/{player}/kick, body {"reason"} optional.
+var AdminKick = requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+	room := adminRoom(w, r)
+	if room == nil {
+		return
+	}
+	body := struct {
+		Reason string `json:"reason"`
+	}{Reason: "admin"}
+	if !readBody(w, r, &body) {
+		return
+	}
+	player := r.PathValue("player")
+	for _, c := range room.clients() {
+		if _, id := c.current(); id == player {
+			c.logger().Info("admin: kicked", "reason", body.Reason)
+			c.disconnect(room, body.Reason)
+			writeJSON(w, http.StatusOK, map[string]interface{}{"kicked": player})
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "unknown player")
+})
+
+// AdminBans serves GET /api/admin/bans (bans in effect) and POST /api/admin/bans:
+// {"name","reason","duration"} bans a player name or account (duration like "1h", empty
+// until the server restarts) and kicks its connected players.
+var AdminBans = requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"bans": banList()})
+		return
+	}
+	var body struct {
+		Name     string `json:"name"`
+		Reason   string `json:"reason"`
+		Duration string `json:"duration"`
+	}
+	if !readBody(w, r, &body) {
+		return
+	}
+	if game.NormalizeName(body.Name) == "" {
+		writeError(w, http.StatusBadRequest, "missing name")
+		return
+	}
+	b := Ban{Name: game.NormalizeName(body.Name), Reason: body.Reason, Created: time.Now()}
+	if body.Duration != "" {
+		d, err := time.ParseDuration(body.Duration)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid duration, expected e.g. 30m or 24h")
+			return
+		}
+		b.Until = b.Created.Add(d)
+	}
+	addBan(b)
+	kicked := kickBanned(b)
+	slog.Info("admin: banned", "name", b.Name, "until", b.Until, "reason", b.Reason)
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"ban": b, "kicked_in": kicked})
+})
+
+// AdminUnban serves DELETE /api/admin/bans/{name}.
+var AdminUnban = requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !removeBan(name) {
+		writeError(w, http.StatusNotFound, "not banned")
+		return
+	}
+	slog.Info("admin: unbanned", "name", name)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"unbanned": name})
+})
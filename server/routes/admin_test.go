@@ -0,0 +1,165 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+	"github.com/gorilla/websocket"
+)
+
+func TestAdminRoomManagement(t *testing.T) {
+	g := game.NewGame(6, 6, 0)
+	useDefaultGame(t, g) // its messages reach the clients of the default room
+	if !g.Running() {
+		g.Start(100)
+	}
+	defer g.Stop()
+	setLive(t, func(l *Live) { l.AdminToken = "s3cret" })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", WS)
+	mux.HandleFunc("GET /api/admin/rooms", AdminRooms)
+	mux.HandleFunc("POST /api/admin/rooms/{id}/{action}", AdminRoomAction)
+	mux.HandleFunc("POST /api/admin/rooms/{id}/sweets", AdminSweets)
+	mux.HandleFunc("POST /api/admin/rooms/{id}/players/{player}/kick", AdminKick)
+	mux.HandleFunc("GET /api/admin/bans", AdminBans)
+	mux.HandleFunc("POST /api/admin/bans", AdminBans)
+	mux.HandleFunc("DELETE /api/admin/bans/{name}", AdminUnban)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	admin := func(method, path, body string) (int, map[string]interface{}) {
+		req, _ := http.NewRequest(method, srv.URL+path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var m map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&m)
+		return resp.StatusCode, m
+	}
+	join := func(name string) (*websocket.Conn, map[string]interface{}) {
+		c, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.WriteJSON(map[string]interface{}{"type": "join", "name": name})
+		c.SetReadDeadline(time.Now().Add(time.Second))
+		defer c.SetReadDeadline(time.Time{})
+		for {
+			var m map[string]interface{}
+			if err := c.ReadJSON(&m); err != nil {
+				t.Fatalf("join %s: %v", name, err)
+			}
+			if m["type"] == "join_ack" || m["type"] == "error" {
+				return c, m
+			}
+		}
+	}
+
+	bob, ack := join("Bob")
+	defer bob.Close()
+	if ack["type"] != "join_ack" {
+		t.Fatalf("join refused: %v", ack)
+	}
+	id := ack["id"].(string)
+
+	code, rooms := admin("GET", "/api/admin/rooms", "")
+	list, _ := rooms["rooms"].([]interface{})
+	if code != http.StatusOK || len(list) == 0 {
+		t.Fatalf("rooms: %d %v", code, rooms)
+	}
+	players := list[0].(map[string]interface{})["players"].([]interface{})
+	if len(players) != 1 || players[0].(map[string]interface{})["name"] != "Bob" {
+		t.Fatalf("unexpected players %v", players)
+	}
+
+	// sweets: random cells, then one on a given cell, which must be free
+	if code, m := admin("POST", "/api/admin/rooms/default/sweets", `{"count":3}`); code != http.StatusOK || m["count"] != 3.0 {
+		t.Fatalf("spawn: %d %v", code, m)
+	}
+	pos := ack["pos"].(map[string]interface{})
+	if code, _ := admin("POST", "/api/admin/rooms/default/sweets", `{"x":`+jsonNumber(pos["x"])+`,"y":`+jsonNumber(pos["y"])+`}`); code != http.StatusConflict {
+		t.Fatalf("sweet on a player: %d", code)
+	}
+	if code, _ := admin("POST", "/api/admin/rooms/default/sweets", `{"x":9,"y":0}`); code != http.StatusBadRequest {
+		t.Fatalf("sweet off the board: %d", code)
+	}
+	if code, _ := admin("POST", "/api/admin/rooms/default/sweets", `{"count":1000}`); code != http.StatusBadRequest {
+		t.Fatalf("too many sweets: %d", code)
+	}
+
+	// pause freezes the tick counter
+	if code, m := admin("POST", "/api/admin/rooms/default/pause", ""); code != http.StatusOK || m["paused"] != true {
+		t.Fatalf("pause: %d %v", code, m)
+	}
+	tick := g.Tick()
+	time.Sleep(50 * time.Millisecond)
+	if g.Tick() != tick {
+		t.Fatal("the tick counter moved while paused")
+	}
+	admin("POST", "/api/admin/rooms/default/resume", "")
+	if code, _ := admin("POST", "/api/admin/rooms/default/fly", ""); code != http.StatusNotFound {
+		t.Fatalf("unknown action: %d", code)
+	}
+
+	// end the round: the players get game_over
+	admin("POST", "/api/admin/rooms/default/end", "")
+	readType(t, bob, "game_over")
+
+	// kick: event, then the connection is closed with 1008
+	if code, _ := admin("POST", "/api/admin/rooms/default/players/"+id+"/kick", `{"reason":"afk"}`); code != http.StatusOK {
+		t.Fatalf("kick: %d", code)
+	}
+	m := readType(t, bob, "event")
+	for m["event"] != "kicked" { // paused/resumed may come first
+		m = readType(t, bob, "event")
+	}
+	if m["reason"] != "afk" {
+		t.Fatalf("unexpected kick event %v", m)
+	}
+	bob.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		if _, _, err := bob.ReadMessage(); err != nil {
+			if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+				t.Fatalf("expected close 1008, got %v", err)
+			}
+			break
+		}
+	}
+
+	// ban: the name is refused until the ban is lifted
+	if code, _ := admin("POST", "/api/admin/bans", `{"name":"bob","reason":"spam","duration":"1h"}`); code != http.StatusCreated {
+		t.Fatalf("ban: %d", code)
+	}
+	c, m := join("Bob")
+	c.Close()
+	if m["code"] != "banned" {
+		t.Fatalf("banned player joined: %v", m)
+	}
+	if _, m := admin("GET", "/api/admin/bans", ""); len(m["bans"].([]interface{})) != 1 {
+		t.Fatalf("unexpected bans %v", m)
+	}
+	if code, _ := admin("DELETE", "/api/admin/bans/Bob", ""); code != http.StatusOK {
+		t.Fatalf("unban: %d", code)
+	}
+	c, m = join("Bob")
+	c.Close()
+	if m["type"] != "join_ack" {
+		t.Fatalf("join after unban: %v", m)
+	}
+}
+
+func jsonNumber(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,120 @@
+package routes
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Admission settings, set by main (see SetLive). 0 means no limit.
+var (
+	// AllowedOrigins lists the origins allowed to open a WebSocket from a browser:
+	// "https://example.com", a host "example.com", "*.example.com" or "*".
+	// Empty allows every origin. Clients that send no Origin (bots, native client) are always allowed.
+	AllowedOrigins []string
+	MaxConns       int // connections to /ws and SSE streams in total
+	MaxConnsPerIP  int // connections to /ws and SSE streams from one IP
+	MaxRoomPlayers int // players in a room, checked at the upgrade and at join
+)
+
+// checkOrigin is the CheckOrigin of the upgrader (a refused origin gets a 403).
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	liveMu.RLock()
+	origins := AllowedOrigins
+	liveMu.RUnlock()
+	if origin == "" || len(origins) == 0 {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range origins {
+		switch {
+		case allowed == "*":
+			return true
+		case strings.Contains(allowed, "://"):
+			if strings.EqualFold(allowed, u.Scheme+"://"+u.Host) {
+				return true
+			}
+		case strings.HasPrefix(allowed, "*."):
+			if strings.HasSuffix(strings.ToLower(u.Hostname()), strings.ToLower(allowed[1:])) {
+				return true
+			}
+		default:
+			if strings.EqualFold(allowed, u.Host) || strings.EqualFold(allowed, u.Hostname()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// admission counts the open WebSocket connections, in total and per IP.
+type admission struct {
+	mu    sync.Mutex
+	total int
+	perIP map[string]int
+}
+
+var adm = admission{perIP: make(map[string]int)}
+
+// clientIP returns the IP of the remote end of r (proxies are not trusted).
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// admit reserves a connection slot for ip. It returns the HTTP status and message
+// to answer if a limit is hit, otherwise 0 and the connection must be released.
+func (a *admission) admit(ip string) (int, string) {
+	maxConns, maxPerIP := connLimits()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if maxConns > 0 && a.total >= maxConns {
+		return http.StatusServiceUnavailable, "server full"
+	}
+	if maxPerIP > 0 && a.perIP[ip] >= maxPerIP {
+		return http.StatusTooManyRequests, "too many connections from this address"
+	}
+	a.total++
+	a.perIP[ip]++
+	return 0, ""
+}
+
+// connLimits returns MaxConns and MaxConnsPerIP, which a config reload may change.
+func connLimits() (maxConns, maxPerIP int) {
+	liveMu.RLock()
+	defer liveMu.RUnlock()
+	return MaxConns, MaxConnsPerIP
+}
+
+// release frees the slot taken by admit.
+func (a *admission) release(ip string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.total--
+	if a.perIP[ip]--; a.perIP[ip] <= 0 {
+		delete(a.perIP, ip)
+	}
+}
+
+// roomFull reports whether room has reached MaxRoomPlayers.
+func roomFull(room *Room) bool {
+	liveMu.RLock()
+	limit := MaxRoomPlayers
+	liveMu.RUnlock()
+	return limit > 0 && room.Game().PlayersCount() >= limit
+}
+
+// refuse answers a refused connection, with a Retry-After so clients back off.
+func refuse(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Retry-After", "10")
+	http.Error(w, msg, status)
+}
@@ -0,0 +1,95 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+	"github.com/gorilla/websocket"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	setLive(t, func(l *Live) { l.Origins = []string{"https://jeu.example.com", "*.games.org", "localhost:3000"} })
+	cases := map[string]bool{
+		"":                        true, // not a browser
+		"https://jeu.example.com": true,
+		"http://jeu.example.com":  false,
+		"https://a.games.org":     true,
+		"http://localhost:3000":   true,
+		"http://localhost:4000":   false,
+		"https://evil.com":        false,
+	}
+	for origin, want := range cases {
+		r := httptest.NewRequest("GET", "/ws", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		if got := checkOrigin(r); got != want {
+			t.Errorf("origin %q: got %v, expected %v", origin, got, want)
+		}
+	}
+}
+
+func TestAdmissionLimits(t *testing.T) {
+	g := game.NewGame(5, 5, 0)
+	g.Start(100)
+	defer g.Stop()
+	useDefaultGame(t, g)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", WS)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+	dial := func() (*websocket.Conn, int) {
+		c, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if err != nil {
+			if resp == nil {
+				t.Fatalf("dial: %v", err)
+			}
+			return nil, resp.StatusCode
+		}
+		return c, http.StatusSwitchingProtocols
+	}
+
+	setLive(t, func(l *Live) { l.MaxConnsPerIP = 1 })
+	c1, status := dial()
+	if c1 == nil {
+		t.Fatalf("first connection refused: %d", status)
+	}
+	if _, status := dial(); status != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 over the per-IP limit, got %d", status)
+	}
+	setLive(t, func(l *Live) { l.MaxConnsPerIP, l.MaxConns = 0, 1 })
+	if _, status := dial(); status != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 over the global limit, got %d", status)
+	}
+	// the slot is released when the client leaves
+	c1.Close()
+	deadline := time.Now().Add(time.Second)
+	for {
+		c, _ := dial()
+		if c != nil {
+			c1 = c
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("slot not released after disconnect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	defer c1.Close()
+
+	// room cap: once one player joined, new connections are refused
+	setLive(t, func(l *Live) { l.MaxConns, l.MaxRoomPlayers = 0, 1 })
+	b, _ := json.Marshal(map[string]interface{}{"type": "join", "name": "First"})
+	c1.WriteMessage(websocket.TextMessage, b)
+	readJoinAck(t, c1)
+	if _, status := dial(); status != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 for a full room, got %d", status)
+	}
+}
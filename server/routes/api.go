@@ -0,0 +1,101 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/store"
+)
+
+const (
+	defaultLimit = 10
+	maxLimit     = 100
+)
+
+// writeJSON sends v as a JSON response with the given status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError sends {"error": msg} with the given status.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]interface{}{"error": msg})
+}
+
+// Leaderboard serves GET /api/leaderboard.
+// Query: period=all|daily (default all), date=YYYY-MM-DD for daily (default today, UTC),
+// room=<id> for the rounds of one room, limit=<n> (default 10, max 100).
+func Leaderboard(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := defaultLimit
+	if l := q.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = min(n, maxLimit)
+	}
+
+	resp := map[string]interface{}{}
+	day, room := "", q.Get("room")
+	switch q.Get("period") {
+	case "", "all":
+		resp["period"] = "all"
+	case "daily":
+		if room != "" {
+			writeError(w, http.StatusBadRequest, "daily leaderboards are not kept per room")
+			return
+		}
+		day = q.Get("date")
+		if day == "" {
+			day = store.DayKey(time.Now())
+		} else if _, err := time.Parse("2006-01-02", day); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid date, expected YYYY-MM-DD")
+			return
+		}
+		resp["period"] = "daily"
+		resp["date"] = day
+	default:
+		writeError(w, http.StatusBadRequest, "invalid period, expected all or daily")
+		return
+	}
+	if room != "" {
+		resp["room"] = room
+	}
+	resp["entries"] = store.Default.Leaderboard(day, room, limit)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// PlayerStats serves GET /api/players/{name}: the persistent profile of a player.
+func PlayerStats(w http.ResponseWriter, r *http.Request) {
+	p, ok := store.Default.Get(r.PathValue("name"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown player")
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// RoomScores serves GET /api/rooms/{id}/scores: the scores of the round being played.
+func RoomScores(w http.ResponseWriter, r *http.Request) {
+	room := getRoom(r.PathValue("id"))
+	if room == nil {
+		writeError(w, http.StatusNotFound, "unknown room")
+		return
+	}
+	g := room.Game()
+	scores := g.Scores()
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"room":        room.ID,
+		"tick":        g.Tick(),
+		"sweets_left": g.SweetsCount(),
+		"scores":      scores,
+	})
+}
@@ -0,0 +1,253 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/anticheat"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/metrics"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/store"
+)
+
+func apiServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/leaderboard", Leaderboard)
+	mux.HandleFunc("GET /api/players/{name}", PlayerStats)
+	mux.HandleFunc("GET /api/rooms/{id}/scores", RoomScores)
+	return httptest.NewServer(mux)
+}
+
+// getJSON fetches url, checks the status and decodes the body into v.
+func getJSON(t *testing.T, url string, status int, v interface{}) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("get %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != status {
+		t.Fatalf("get %s: status %d, expected %d", url, resp.StatusCode, status)
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("decode %s: %v", url, err)
+		}
+	}
+}
+
+func TestAPILeaderboardAndPlayer(t *testing.T) {
+	store.Default = store.New()
+	store.Default.RecordRound(DefaultRoom, []store.Result{{Name: "Alice", Score: 5}, {Name: "Bob", Score: 2}})
+	srv := apiServer()
+	defer srv.Close()
+
+	var lb struct {
+		Period  string        `json:"period"`
+		Entries []store.Entry `json:"entries"`
+	}
+	getJSON(t, srv.URL+"/api/leaderboard", http.StatusOK, &lb)
+	if lb.Period != "all" || len(lb.Entries) != 2 || lb.Entries[0].Name != "Alice" {
+		t.Fatalf("unexpected leaderboard %+v", lb)
+	}
+	getJSON(t, srv.URL+"/api/leaderboard?period=daily&limit=1", http.StatusOK, &lb)
+	if lb.Period != "daily" || len(lb.Entries) != 1 {
+		t.Fatalf("unexpected daily leaderboard %+v", lb)
+	}
+	getJSON(t, srv.URL+"/api/leaderboard?room=default", http.StatusOK, &lb)
+	if len(lb.Entries) != 2 {
+		t.Fatalf("unexpected room leaderboard %+v", lb)
+	}
+	getJSON(t, srv.URL+"/api/leaderboard?period=weekly", http.StatusBadRequest, nil)
+	getJSON(t, srv.URL+"/api/leaderboard?limit=-3", http.StatusBadRequest, nil)
+
+	var p store.Profile
+	getJSON(t, srv.URL+"/api/players/Bob", http.StatusOK, &p)
+	if p.Name != "Bob" || p.Rounds != 1 || p.Sweets != 2 {
+		t.Fatalf("unexpected profile %+v", p)
+	}
+	getJSON(t, srv.URL+"/api/players/Nobody", http.StatusNotFound, nil)
+}
+
+func TestAPIRoomScores(t *testing.T) {
+	g := game.NewGame(4, 4, 0)
+	a := g.AddPlayer("A")
+	g.SetPlayerPosition(a.ID, 0, 0)
+	b := g.AddPlayer("B")
+	g.SetPlayerPosition(b.ID, 3, 3)
+	g.SetSweet("s1", 3, 2)
+	g.SetSweet("s2", 0, 3)
+	g.PushCommand(game.Command{PlayerID: b.ID, Type: "move", Dir: "up"})
+	useDefaultGame(t, g)
+	if !g.Running() {
+		g.Start(100)
+	}
+	defer g.Stop()
+	srv := apiServer()
+	defer srv.Close()
+
+	var rs struct {
+		Room       string       `json:"room"`
+		SweetsLeft int          `json:"sweets_left"`
+		Scores     []game.Score `json:"scores"`
+	}
+	for i := 0; i < 50; i++ {
+		getJSON(t, srv.URL+"/api/rooms/default/scores", http.StatusOK, &rs)
+		if rs.SweetsLeft == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if rs.Room != DefaultRoom || rs.SweetsLeft != 1 || len(rs.Scores) != 2 || rs.Scores[0].ID != b.ID || rs.Scores[0].Score != 1 {
+		t.Fatalf("unexpected room scores %+v", rs)
+	}
+	getJSON(t, srv.URL+"/api/rooms/nope/scores", http.StatusNotFound, nil)
+}
+
+func TestAdminCheatReport(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/admin/anticheat", CheatReport)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	getJSON(t, srv.URL+"/api/admin/anticheat", http.StatusForbidden, nil) // disabled without a token
+	setLive(t, func(l *Live) { l.AdminToken = "s3cret" })
+	getJSON(t, srv.URL+"/api/admin/anticheat", http.StatusUnauthorized, nil)
+
+	anticheat.Default = anticheat.New(anticheat.DefaultConfig)
+	anticheat.Default.Move("default/p-1", "Cheater", "up", true, time.Now())
+	req, _ := http.NewRequest("GET", srv.URL+"/api/admin/anticheat", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var report struct {
+		Players []anticheat.Entry `json:"players"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("report: %d %v", resp.StatusCode, err)
+	}
+	if len(report.Players) != 1 || report.Players[0].Name != "Cheater" {
+		t.Fatalf("unexpected report %+v", report.Players)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	srv := httptest.NewServer(metrics.Default.Handler())
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	for _, name := range []string{"ws_connected_clients", "room_players{room=\"default\"}", "game_tick_duration_seconds_bucket", "hub_send_overflows_total", "ws_sent_bytes_per_second", "game_tick_overruns_total"} {
+		if !strings.Contains(string(b), name) {
+			t.Errorf("metric %s missing", name)
+		}
+	}
+}
+
+func TestHealthEndpoint(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(Health))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Status string `json:"status"`
+		Rooms  []struct {
+			ID   string         `json:"id"`
+			Loop game.LoopStats `json:"loop"`
+		} `json:"rooms"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Status == "" || len(body.Rooms) == 0 || body.Rooms[0].ID != DefaultRoom {
+		t.Fatalf("unexpected health %+v", body)
+	}
+}
+
+func TestProbes(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", Healthz)
+	mux.HandleFunc("GET /readyz", Readyz)
+	mux.HandleFunc("GET /info", Info)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	get := func(path string, v interface{}) int {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		json.NewDecoder(resp.Body).Decode(v)
+		return resp.StatusCode
+	}
+
+	var alive map[string]interface{}
+	if code := get("/healthz", &alive); code != http.StatusOK || alive["status"] != "ok" {
+		t.Fatalf("healthz: %d %v", code, alive)
+	}
+	var ready map[string]interface{}
+	if code := get("/readyz", &ready); code != http.StatusOK || ready["ready"] != true {
+		t.Fatalf("readyz: %d %v", code, ready)
+	}
+
+	var info struct {
+		Version  string `json:"version"`
+		Protocol int    `json:"protocol"`
+		Rooms    []struct {
+			ID   string         `json:"id"`
+			TPS  int            `json:"tps"`
+			Grid map[string]int `json:"grid"`
+			Mode string         `json:"mode"`
+		} `json:"rooms"`
+	}
+	if code := get("/info", &info); code != http.StatusOK || info.Version == "" || info.Protocol != ProtocolVersion {
+		t.Fatalf("info: %d %+v", code, info)
+	}
+	if len(info.Rooms) == 0 || info.Rooms[0].TPS <= 0 || info.Rooms[0].Grid["w"] != game.Default.W || info.Rooms[0].Mode != "classic" {
+		t.Fatalf("unexpected rooms %+v", info.Rooms)
+	}
+
+	// the connection limit, a live setting, reached: not ready
+	setLive(t, func(l *Live) { l.MaxConns = 1 })
+	if status, _ := adm.admit("192.0.2.1"); status == 0 {
+		defer adm.release("192.0.2.1")
+	}
+	ready = nil
+	if code := get("/readyz", &ready); code != http.StatusServiceUnavailable || !strings.Contains(fmt.Sprint(ready["reasons"]), "connection limit reached") {
+		t.Fatalf("readyz at the connection limit: %d %v", code, ready)
+	}
+
+	// a game loop that does not run while the room is awake: not ready
+	stopped := game.NewGame(10, 10, 1)
+	useDefaultGame(t, stopped)
+	stopped.Stop() // started by SetDefaultGame if the previous game ran
+	room := getRoom(DefaultRoom)
+	room.mu.Lock()
+	sleeping := room.sleeping
+	room.sleeping = false
+	room.mu.Unlock()
+	defer func() {
+		room.mu.Lock()
+		room.sleeping = sleeping
+		room.mu.Unlock()
+	}()
+	ready = nil
+	if code := get("/readyz", &ready); code != http.StatusServiceUnavailable || ready["ready"] != false {
+		t.Fatalf("readyz with a stopped loop: %d %v", code, ready)
+	}
+}
@@ -0,0 +1,113 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/auth"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+)
+
+// RequireAuth rejects WebSocket connections without a valid token (set by main with -auth).
+// Otherwise a token is optional, but the names of registered accounts can only be used with one.
+var RequireAuth = false
+
+// credentials is the body of POST /api/login and POST /api/register.
+type credentials struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	APIKey   string `json:"api_key"` // login only, instead of name and password
+}
+
+func readCredentials(w http.ResponseWriter, r *http.Request) (credentials, bool) {
+	var c credentials
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&c); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return c, false
+	}
+	return c, true
+}
+
+// Login serves POST /api/login: {"name","password"} or {"api_key"} -> {"token","name","expires_at"}.
+func Login(w http.ResponseWriter, r *http.Request) {
+	c, ok := readCredentials(w, r)
+	if !ok {
+		return
+	}
+	var name string
+	var err error
+	if c.APIKey != "" {
+		name, err = auth.Default.CheckAPIKey(c.APIKey)
+	} else {
+		// as at register: " alice " logs in to "Alice"
+		name, err = auth.Default.Check(game.NormalizeName(c.Name), c.Password)
+	}
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	writeToken(w, http.StatusOK, name)
+}
+
+// Register serves POST /api/register: creates an account and logs it in.
+func Register(w http.ResponseWriter, r *http.Request) {
+	c, ok := readCredentials(w, r)
+	if !ok {
+		return
+	}
+	// account names follow the player name rules, the token name is used as is at join
+	c.Name = game.NormalizeName(c.Name)
+	if err := game.ValidateName(c.Name); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	err := auth.Default.Register(c.Name, c.Password)
+	switch {
+	case errors.Is(err, auth.ErrExists):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case errors.Is(err, auth.ErrBadPassword):
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "unable to save account")
+		return
+	}
+	writeToken(w, http.StatusCreated, c.Name)
+}
+
+func writeToken(w http.ResponseWriter, status int, name string) {
+	token, exp := auth.Tokens.Issue(name)
+	writeJSON(w, status, map[string]interface{}{"token": token, "name": name, "expires_at": exp.UTC().Format(time.RFC3339)})
+}
+
+// authenticate returns the account of the token sent with r ("Authorization: Bearer <token>",
+// or ?token= since browsers cannot set headers on a WebSocket), "" if there is none.
+func authenticate(r *http.Request) (string, error) {
+	token := r.URL.Query().Get("token")
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		token = strings.TrimPrefix(h, "Bearer ")
+	}
+	if token == "" {
+		if RequireAuth {
+			return "", auth.ErrBadToken
+		}
+		return "", nil
+	}
+	return auth.Tokens.Verify(token)
+}
+
+// playerName returns the name a client plays under: its account if it has a token,
+// otherwise the requested name unless it belongs to an account.
+func (c *Client) playerName(requested string) (string, bool) {
+	if c.account != "" {
+		return c.account, true
+	}
+	if requested != "" && auth.Default.Exists(game.NormalizeName(requested)) {
+		return "", false
+	}
+	return requested, true
+}
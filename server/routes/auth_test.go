@@ -0,0 +1,109 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/auth"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+	"github.com/gorilla/websocket"
+)
+
+// readType returns the first message of the given type.
+func readType(t *testing.T, c *websocket.Conn, typ string) map[string]interface{} {
+	t.Helper()
+	// a single deadline: after a timeout the connection cannot be read anymore
+	c.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+	defer c.SetReadDeadline(time.Time{})
+	for {
+		_, msg, err := c.ReadMessage()
+		if err != nil {
+			break
+		}
+		var m map[string]interface{}
+		if json.Unmarshal(msg, &m) == nil && m["type"] == typ {
+			return m
+		}
+	}
+	t.Fatalf("no %s message received", typ)
+	return nil
+}
+
+func TestAuthLoginAndJoin(t *testing.T) {
+	auth.Default = auth.New()
+	if err := auth.Default.Register("Alice", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	g := game.NewGame(5, 5, 0)
+	g.Start(100)
+	defer g.Stop()
+	useDefaultGame(t, g)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", WS)
+	mux.HandleFunc("POST /api/login", Login)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	login := func(body string) (*http.Response, map[string]interface{}) {
+		resp, err := http.Post(srv.URL+"/api/login", "application/json", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var m map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&m)
+		return resp, m
+	}
+	if resp, _ := login(`{"name":"Alice","password":"wrong password"}`); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("bad password: status %d", resp.StatusCode)
+	}
+	resp, m := login(`{"name":"Alice","password":"correct horse"}`)
+	token, _ := m["token"].(string)
+	if resp.StatusCode != http.StatusOK || token == "" {
+		t.Fatalf("login failed: %d %v", resp.StatusCode, m)
+	}
+	// normalized and case-insensitive as at register, the token holds the account name
+	if resp, m := login(`{"name":"  alice ","password":"correct horse"}`); resp.StatusCode != http.StatusOK || m["name"] != "Alice" {
+		t.Fatalf("login as \"  alice \": %d %v", resp.StatusCode, m)
+	}
+
+	// with a token, the declared name is ignored
+	c, _, err := websocket.DefaultDialer.Dial(wsURL+"?token="+token, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	b, _ := json.Marshal(map[string]interface{}{"type": "join", "name": "Bob"})
+	c.WriteMessage(websocket.TextMessage, b)
+	id := readJoinAck(t, c)
+	if p := g.GetPlayer(id); p == nil || p.Name != "Alice" {
+		t.Fatalf("player should be bound to the token account, got %+v", p)
+	}
+
+	// without a token, a registered name is refused
+	anon, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer anon.Close()
+	b, _ = json.Marshal(map[string]interface{}{"type": "join", "name": "Alice"})
+	anon.WriteMessage(websocket.TextMessage, b)
+	readType(t, anon, "error")
+
+	// a bad token is refused at the upgrade, and so is no token with RequireAuth
+	if _, resp, err := websocket.DefaultDialer.Dial(wsURL+"?token=forged.token", nil); err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a bad token")
+	}
+	RequireAuth = true
+	defer func() { RequireAuth = false }()
+	if _, resp, err := websocket.DefaultDialer.Dial(wsURL, nil); err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token")
+	}
+}
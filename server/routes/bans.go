@@ -0,0 +1,132 @@
+package routes
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+)
+
+// Ban refuses a player name (or account) at join and queue. Bans are kept in memory.
+type Ban struct {
+	Name    string    `json:"name"`
+	Reason  string    `json:"reason,omitempty"`
+	Created time.Time `json:"created"`
+	Until   time.Time `json:"until,omitempty"`  // zero: until the server restarts
+	Config  bool      `json:"config,omitempty"` // from the config (auth.bans), replaced at each reload
+}
+
+var bans = struct {
+	mu sync.Mutex
+	m  map[string]Ban // key: banKey(name)
+}{m: make(map[string]Ban)}
+
+func banKey(name string) string {
+	return strings.ToLower(game.NormalizeName(name))
+}
+
+func addBan(b Ban) {
+	bans.mu.Lock()
+	bans.m[banKey(b.Name)] = b
+	bans.mu.Unlock()
+}
+
+// removeBan lifts the ban of name, it returns false if there was none.
+func removeBan(name string) bool {
+	bans.mu.Lock()
+	defer bans.mu.Unlock()
+	_, ok := bans.m[banKey(name)]
+	delete(bans.m, banKey(name))
+	return ok
+}
+
+// banned returns the ban of name, if any and not expired.
+func banned(name string) (Ban, bool) {
+	bans.mu.Lock()
+	defer bans.mu.Unlock()
+	b, ok := bans.m[banKey(name)]
+	if ok && !b.Until.IsZero() && time.Now().After(b.Until) {
+		delete(bans.m, banKey(name))
+		return Ban{}, false
+	}
+	return b, ok
+}
+
+// banList returns the bans in effect, sorted by name.
+func banList() []Ban {
+	now := time.Now()
+	bans.mu.Lock()
+	defer bans.mu.Unlock()
+	list := make([]Ban, 0, len(bans.m))
+	for k, b := range bans.m {
+		if !b.Until.IsZero() && now.After(b.Until) {
+			delete(bans.m, k)
+			continue
+		}
+		list = append(list, b)
+	}
+	sort.Slice(list, func(i, j int) bool { return banKey(list[i].Name) < banKey(list[j].Name) })
+	return list
+}
+
+// setConfigBans replaces the bans of the config by names and kicks the players they ban.
+// A name the admin API banned keeps that ban.
+func setConfigBans(names []string) {
+	keep := make(map[string]bool, len(names))
+	var added []Ban
+	now := time.Now()
+	bans.mu.Lock()
+	for _, name := range names {
+		keep[banKey(name)] = true
+		if _, ok := bans.m[banKey(name)]; !ok {
+			b := Ban{Name: game.NormalizeName(name), Created: now, Config: true}
+			bans.m[banKey(name)] = b
+			added = append(added, b)
+		}
+	}
+	for k, b := range bans.m {
+		if b.Config && !keep[k] {
+			delete(bans.m, k)
+			slog.Info("ban lifted by the config", "name", b.Name)
+		}
+	}
+	bans.mu.Unlock()
+	for _, b := range added {
+		slog.Info("banned by the config", "name", b.Name, "kicked_in", kickBanned(b))
+	}
+}
+
+// kickBanned disconnects the clients playing under the name or account of b and
+// returns the rooms they were in.
+func kickBanned(b Ban) []string {
+	reason := "banned"
+	if b.Reason != "" {
+		reason += ": " + b.Reason
+	}
+	kicked := make([]string, 0)
+	for _, room := range Rooms() {
+		for _, c := range room.clients() {
+			c.mu.Lock()
+			match := (c.playerID != "" && banKey(c.name) == banKey(b.Name)) || (c.account != "" && banKey(c.account) == banKey(b.Name))
+			c.mu.Unlock()
+			if match {
+				c.logger().Info("kicked", "reason", reason)
+				c.disconnect(room, reason)
+				kicked = append(kicked, room.ID)
+			}
+		}
+	}
+	return kicked
+}
+
+// banError is the answer to a join or queue of a banned player.
+func banError(b Ban) map[string]interface{} {
+	msg := "banned"
+	if b.Reason != "" {
+		msg += ": " + b.Reason
+	}
+	return map[string]interface{}{"type": "error", "code": "banned", "message": msg}
+}
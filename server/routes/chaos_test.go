@@ -25,17 +25,18 @@ func TestChaos_MonkeyCrowd(t *testing.T) {
 	}
 
 	// Start test server on a random port
-	game.Default = game.NewGame(20, 20, 50) 
-	game.Default.Start(10)
+	g := game.NewGame(20, 20, 50)
+	g.Start(10)
+	useDefaultGame(t, g)
 
 	// Forward game broadcasts to hub
 	go func() {
-		for b := range game.Default.StateBroadcast {
+		for b := range g.StateBroadcast {
 			h.broadcast <- b
 		}
 	}()
 	go func() {
-		for b := range game.Default.EventBroadcast {
+		for b := range g.EventBroadcast {
 			h.broadcast <- b
 		}
 	}()
@@ -29,7 +29,7 @@ func TestE2E_MultiClientCollect(t *testing.T) {
 	g.ClearSweets()
 	g.SetSweet("s1", 2, 2)
 	g.Start(100)
-	game.Default = g
+	useDefaultGame(t, g)
 	// forward this test game broadcasts to the hub so it delivers to connected clients
 	go func() {
 		for b := range g.StateBroadcast {
@@ -65,16 +65,15 @@ func TestE2E_MultiClientCollect(t *testing.T) {
 		return c, id
 	}
 
+	// position players around the sweet, each before the next joins (a taken cell is refused)
 	c1, id1 := dialJoin("A")
 	defer c1.Close()
+	g.SetPlayerPosition(id1, 1, 2) // left
 	c2, id2 := dialJoin("B")
 	defer c2.Close()
-
-	// position players around the sweet
-	g.SetPlayerPosition(id1, 1, 2) // left
 	g.SetPlayerPosition(id2, 3, 2) // right
 
-	// c2 moves left then c1 moves right, c2 should collect
+	// c2 moves left then c1 moves right, one of them collects (tick priority decides)
 	moveL := map[string]interface{}{"type": "move", "dir": "left"}
 	mbL, _ := jsonMarshal(moveL)
 	moveR := map[string]interface{}{"type": "move", "dir": "right"}
@@ -116,7 +115,7 @@ func TestE2E_MultiClientCollect(t *testing.T) {
 	// verify server state
 	p1 := g.GetPlayer(id1)
 	p2 := g.GetPlayer(id2)
-	if !(p2.Score == 1 && g.SweetsCount() == 0) {
+	if !(p1.Score+p2.Score == 1 && g.SweetsCount() == 0) {
 		t.Fatalf("unexpected server state after collect: p1=%+v p2=%+v sweets=%d", p1, p2, g.SweetsCount())
 	}
 }
@@ -0,0 +1,151 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// MinTPS is the lowest tick rate a matched room may drop to under load, 0 keeps the rate fixed
+// (see game.SetAdaptiveTPS).
+var MinTPS int
+
+var started = time.Now()
+
+// Health serves GET /health: load of the server and of the game loop of each room.
+// The status is "degraded" when a loop cannot keep up with its tick rate or had to lower it.
+func Health(w http.ResponseWriter, r *http.Request) {
+	status, load := "ok", 0.0
+	list := make([]map[string]interface{}, 0)
+	for _, room := range Rooms() {
+		g := room.Game()
+		s := g.LoopStats()
+		if !room.Sleeping() && (s.Load > 1 || s.TPS < s.BaseTPS) {
+			status = "degraded"
+		}
+		load = max(load, s.Load)
+		list = append(list, map[string]interface{}{"id": room.ID, "players": g.PlayersCount(), "sleeping": room.Sleeping(), "loop": s})
+	}
+	adm.mu.Lock()
+	clients := adm.total
+	adm.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":     status,
+		"uptime_s":   int(time.Since(started).Seconds()),
+		"load":       load, // highest room load
+		"clients":    clients,
+		"goroutines": runtime.NumGoroutine(),
+		"rooms":      list,
+	})
+}
+
+// Healthz serves GET /healthz: the process is alive and serving HTTP.
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "ok"})
+}
+
+// stallAfter is how long a game loop may go without a tick (outside the pause between
+// rounds) before the server is reported not ready.
+const stallAfter = time.Second
+
+// Readyz serves GET /readyz: 200 when every game loop ticks within its budget and new
+// connections are accepted, 503 with the reasons otherwise.
+func Readyz(w http.ResponseWriter, r *http.Request) {
+	if reasons := notReady(time.Now()); len(reasons) > 0 {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"ready": false, "reasons": reasons})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ready": true})
+}
+
+// notReady returns why the server should not get new players, nil if it is ready.
+func notReady(now time.Time) []string {
+	var reasons []string
+	for _, room := range Rooms() {
+		if room.Sleeping() {
+			continue // stopped on purpose, wakes up with the next client
+		}
+		s := room.Game().LoopStats()
+		switch {
+		case !s.Running:
+			reasons = append(reasons, fmt.Sprintf("room %s: game loop stopped", room.ID))
+		case !s.BetweenRounds && now.Sub(s.LastTickAt) > stallAfter:
+			reasons = append(reasons, fmt.Sprintf("room %s: no tick for %v", room.ID, now.Sub(s.LastTickAt).Round(time.Millisecond)))
+		case s.Load > 1:
+			reasons = append(reasons, fmt.Sprintf("room %s: tick budget exceeded (load %.2f)", room.ID, s.Load))
+		}
+	}
+	maxConns, _ := connLimits()
+	adm.mu.Lock()
+	full := maxConns > 0 && adm.total >= maxConns
+	adm.mu.Unlock()
+	if full {
+		reasons = append(reasons, "connection limit reached")
+	}
+	if roomFull(getRoom(DefaultRoom)) {
+		reasons = append(reasons, "default room full")
+	}
+	return reasons
+}
+
+// ProtocolVersion is the version of the WebSocket protocol (server/PROTOCOL.md),
+// raised on changes that break existing clients.
+const ProtocolVersion = 1
+
+// Version is the build version shown by /info. Set it with
+// -ldflags "-X github.com/dntelisa/SR-S9-Projet-Serveur/server/routes.Version=v1.2.0",
+// otherwise it comes from the module version and VCS revision recorded by go build.
+var Version string
+
+func buildVersion() string {
+	if Version != "" {
+		return Version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	v := info.Main.Version
+	for _, s := range info.Settings {
+		switch {
+		case s.Key == "vcs.revision":
+			v += " " + s.Value[:min(len(s.Value), 12)]
+		case s.Key == "vcs.modified" && s.Value == "true":
+			v += "-dirty"
+		}
+	}
+	return v
+}
+
+// Info serves GET /info: build and protocol versions, uptime and the settings of each room.
+func Info(w http.ResponseWriter, r *http.Request) {
+	list := make([]map[string]interface{}, 0)
+	for _, room := range Rooms() {
+		g := room.Game()
+		s := g.LoopStats()
+		entry := map[string]interface{}{
+			"id":       room.ID,
+			"players":  g.PlayersCount(),
+			"tps":      s.TPS,
+			"base_tps": s.BaseTPS,
+			"grid":     map[string]int{"w": g.W, "h": g.H},
+			"mode":     "classic",
+			"sleeping": room.Sleeping(),
+		}
+		if m := g.Endless(); m != nil {
+			entry["mode"] = "endless"
+			entry["endless"] = m
+		}
+		list = append(list, entry)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"version":    buildVersion(),
+		"protocol":   ProtocolVersion,
+		"go":         runtime.Version(),
+		"started_at": started.UTC().Format(time.RFC3339),
+		"uptime_s":   int(time.Since(started).Seconds()),
+		"rooms":      list,
+	})
+}
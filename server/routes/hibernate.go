@@ -0,0 +1,111 @@
+package routes
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/metrics"
+)
+
+// Idle rooms hibernate: once no client (player or spectator) is left, the game loop
+// stops, so no tick and no state to marshal. The next client wakes the room up.
+// Matched rooms, that nobody can come back to, are removed after RoomIdleTimeout.
+
+// RoomIdleTimeout is how long an empty matched room sleeps before it is removed,
+// 0 to remove it as soon as it is empty. The default room is never removed.
+var RoomIdleTimeout = time.Minute
+
+// gcInterval is how often the sleeping rooms are checked.
+const gcInterval = 5 * time.Second
+
+func init() {
+	metrics.NewGaugeFunc("rooms", "Rooms, awake or hibernating.", func() float64 {
+		return float64(len(Rooms()))
+	})
+	metrics.NewGaugeFunc("rooms_hibernating", "Rooms with no client, whose game loop is stopped.", func() float64 {
+		n := 0
+		for _, r := range Rooms() {
+			if r.Sleeping() {
+				n++
+			}
+		}
+		return float64(n)
+	})
+	getRoom(DefaultRoom).idle() // nobody connected yet
+}
+
+// CollectRooms removes the matched rooms asleep for RoomIdleTimeout, checking every
+// gcInterval. main runs it; the tests call collectIdleRooms at the time they want.
+func CollectRooms() {
+	for now := range time.Tick(gcInterval) {
+		collectIdleRooms(now)
+	}
+}
+
+// enter adds c to the room, waking it up if it sleeps.
+func (r *Room) enter(c *Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hub.add(c)
+	if !r.sleeping {
+		return
+	}
+	r.sleeping = false
+	g := r.Game()
+	if !g.Running() {
+		tps := g.LoopStats().BaseTPS
+		if tps == 0 {
+			tps = TickRate
+		}
+		g.Start(tps)
+	}
+	slog.Info("room woken up", "room", r.ID, "slept", time.Since(r.idleSince).Round(time.Second))
+}
+
+// idle puts the room to sleep if no client is left in it, called when a client leaves.
+func (r *Room) idle() {
+	r.mu.Lock()
+	if r.sleeping || r.hub.count() > 0 {
+		r.mu.Unlock()
+		return
+	}
+	if r.done != nil && roomIdleTimeout() == 0 {
+		r.mu.Unlock()
+		r.close()
+		return
+	}
+	r.sleeping, r.idleSince = true, time.Now()
+	r.Game().Stop()
+	r.mu.Unlock()
+	slog.Info("room hibernating", "room", r.ID)
+}
+
+// Sleeping reports whether the room hibernates.
+func (r *Room) Sleeping() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sleeping
+}
+
+// roomIdleTimeout returns RoomIdleTimeout, which a config reload may change.
+func roomIdleTimeout() time.Duration {
+	liveMu.RLock()
+	defer liveMu.RUnlock()
+	return RoomIdleTimeout
+}
+
+// collectIdleRooms removes the matched rooms asleep for RoomIdleTimeout at now.
+func collectIdleRooms(now time.Time) {
+	timeout := roomIdleTimeout()
+	for _, r := range Rooms() {
+		if r.done == nil {
+			continue
+		}
+		r.mu.Lock()
+		expired := r.sleeping && now.Sub(r.idleSince) >= timeout
+		r.mu.Unlock()
+		if expired {
+			r.close()
+		}
+	}
+}
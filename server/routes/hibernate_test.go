@@ -0,0 +1,85 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+	"github.com/gorilla/websocket"
+)
+
+func TestRoomHibernation(t *testing.T) {
+	setLive(t, func(l *Live) { l.RoomIdle = time.Minute })
+	g := game.NewGame(5, 5, 0)
+	r := newRoom(g)
+	c := &Client{send: make(chan []byte, 16), room: r}
+	r.enter(c)
+
+	r.hub.remove(c)
+	r.idle()
+	if !r.Sleeping() || g.Running() {
+		t.Fatal("empty room should hibernate")
+	}
+	tick := g.Tick()
+	time.Sleep(50 * time.Millisecond)
+	if g.Tick() != tick {
+		t.Fatal("a hibernating room ticked")
+	}
+
+	r.enter(c)
+	if r.Sleeping() || !g.Running() {
+		t.Fatal("a client should wake the room up")
+	}
+	r.hub.remove(c)
+	r.idle()
+
+	collectIdleRooms(time.Now().Add(30 * time.Second))
+	if getRoom(r.ID) == nil {
+		t.Fatal("room removed before its idle timeout")
+	}
+	collectIdleRooms(time.Now().Add(2 * time.Minute))
+	if getRoom(r.ID) != nil {
+		t.Fatal("idle room not removed")
+	}
+}
+
+func TestDefaultRoomWakesOnConnect(t *testing.T) {
+	g := game.NewGame(5, 5, 0) // not started: the room starts it when it wakes up
+	defer g.Stop()
+	room := getRoom(DefaultRoom)
+	room.idle() // no client left from other tests
+	useDefaultGame(t, g)
+	if !room.Sleeping() {
+		t.Fatal("default room should sleep without clients")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", WS)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	c, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.WriteJSON(map[string]interface{}{"type": "join", "name": "Sleepy"})
+	readType(t, c, "join_ack")
+	if room.Sleeping() || !g.Running() {
+		t.Fatal("connecting should wake the default room up")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if g.Tick() == 0 {
+		t.Fatal("the woken up game does not tick")
+	}
+
+	c.Close()
+	deadline := time.Now().Add(time.Second)
+	for !room.Sleeping() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !room.Sleeping() || g.Running() {
+		t.Fatal("default room should sleep again once the client left")
+	}
+}
@@ -0,0 +1,71 @@
+package routes
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/anticheat"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+)
+
+// Some settings change while the server runs, when the config is reloaded: they are
+// read under liveMu and only SetLive changes them, in the tests too.
+var liveMu sync.RWMutex
+
+// MOTD is the message of the day, sent in join_ack (empty for none).
+var MOTD string
+
+// Live are the settings SetLive applies without dropping any connection.
+type Live struct {
+	MOTD                                    string
+	Rules                                   game.Rules // from the next round of each room
+	Origins                                 []string
+	MaxConns, MaxConnsPerIP, MaxRoomPlayers int
+	MsgRate, MsgBurst                       float64
+	RoomIdle                                time.Duration
+	AdminToken                              string
+	Bans                                    []string // banned by the config, the bans of the admin API are kept
+	MatchSize                               int      // matchmaking, from the next groups formed
+	MatchWindow, MatchWiden                 float64
+}
+
+// SetLive applies l to the next connections, messages and joins, and to the next round
+// of every room. The anti-cheat move rate follows the rules. The clients get a new MOTD at once, players now banned are kicked.
+func SetLive(l Live) {
+	liveMu.Lock()
+	newMOTD := l.MOTD != MOTD
+	MOTD = l.MOTD
+	RoomRules = l.Rules
+	AllowedOrigins = l.Origins
+	MaxConns, MaxConnsPerIP, MaxRoomPlayers = l.MaxConns, l.MaxConnsPerIP, l.MaxRoomPlayers
+	MsgRate, MsgBurst = l.MsgRate, l.MsgBurst
+	RoomIdleTimeout = l.RoomIdle
+	AdminToken = l.AdminToken
+	liveMu.Unlock()
+
+	for _, r := range Rooms() {
+		if err := r.Game().SetNextRules(l.Rules); err != nil {
+			slog.Error("rules not applied", "room", r.ID, "err", err)
+		}
+	}
+	if newMOTD && l.MOTD != "" {
+		b, _ := json.Marshal(map[string]interface{}{"type": "event", "event": "motd", "message": l.MOTD})
+		for _, r := range Rooms() {
+			for _, c := range r.clients() {
+				c.trySend(b)
+			}
+		}
+	}
+	anticheat.Default.SetMoveLimit(TickRate, l.Rules.MaxMoves)
+	setConfigBans(l.Bans)
+	SetMatchmaking(l.MatchSize, l.MatchWindow, l.MatchWiden)
+}
+
+// motd returns the message of the day.
+func motd() string {
+	liveMu.RLock()
+	defer liveMu.RUnlock()
+	return MOTD
+}
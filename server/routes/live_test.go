@@ -0,0 +1,122 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+	"github.com/gorilla/websocket"
+)
+
+// currentLive returns the live settings in effect.
+func currentLive() Live {
+	size, window, widen := mm.settings()
+	liveMu.RLock()
+	defer liveMu.RUnlock()
+	return Live{MOTD: MOTD, Rules: RoomRules, Origins: AllowedOrigins, MaxConns: MaxConns, MaxConnsPerIP: MaxConnsPerIP,
+		MaxRoomPlayers: MaxRoomPlayers, MsgRate: MsgRate, MsgBurst: MsgBurst, RoomIdle: RoomIdleTimeout, AdminToken: AdminToken,
+		MatchSize: size, MatchWindow: window, MatchWiden: widen}
+}
+
+// setLive changes the live settings through SetLive, as a reload does, until the end of the test.
+func setLive(t *testing.T, change func(l *Live)) {
+	saved := currentLive()
+	l := saved
+	change(&l)
+	SetLive(l)
+	t.Cleanup(func() { SetLive(saved) })
+}
+
+func TestSetLive(t *testing.T) {
+	g := game.NewGame(6, 6, 0)
+	g.Start(100)
+	defer g.Stop()
+	useDefaultGame(t, g)
+	saved := currentLive()
+	defer SetLive(saved)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", WS)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	join := func(name string) (*websocket.Conn, map[string]interface{}) {
+		c, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.WriteJSON(map[string]interface{}{"type": "join", "name": name})
+		c.SetReadDeadline(time.Now().Add(time.Second))
+		defer c.SetReadDeadline(time.Time{})
+		for {
+			var m map[string]interface{}
+			if err := c.ReadJSON(&m); err != nil {
+				t.Fatalf("join %s: %v", name, err)
+			}
+			if m["type"] == "join_ack" || m["type"] == "error" {
+				return c, m
+			}
+		}
+	}
+	event := func(c *websocket.Conn, name string) map[string]interface{} {
+		c.SetReadDeadline(time.Now().Add(time.Second))
+		defer c.SetReadDeadline(time.Time{})
+		for {
+			var m map[string]interface{}
+			if err := c.ReadJSON(&m); err != nil {
+				t.Fatalf("no %s event: %v", name, err)
+			}
+			if m["type"] == "event" && m["event"] == name {
+				return m
+			}
+		}
+	}
+
+	troll, _ := join("Troll")
+	defer troll.Close()
+	addBan(Ban{Name: "Keep", Created: time.Now()}) // by the admin API
+	defer removeBan("Keep")
+
+	l := saved
+	l.MOTD = "Bienvenue !"
+	l.Rules = game.Rules{Sweets: 3, MaxMoves: 1, Intermission: time.Second}
+	l.Bans = []string{"troll", "Keep"}
+	SetLive(l)
+	if m := event(troll, "motd"); m["message"] != "Bienvenue !" {
+		t.Fatalf("unexpected motd event %v", m)
+	}
+	if m := event(troll, "kicked"); !strings.HasPrefix(m["reason"].(string), "banned") {
+		t.Fatalf("unexpected kick %v", m)
+	}
+	reader, ack := join("Reader")
+	defer reader.Close()
+	if ack["motd"] != "Bienvenue !" {
+		t.Fatalf("join_ack without the motd: %v", ack)
+	}
+	refused, ack := join("TROLL")
+	refused.Close()
+	if ack["code"] != "banned" {
+		t.Fatalf("expected the banned code, got %v", ack)
+	}
+
+	// the rules wait for the next round
+	if g.Rules().MaxMoves != game.DefaultRules.MaxMoves {
+		t.Fatal("rules changed in the middle of a round")
+	}
+	g.Restart()
+	if g.Rules() != l.Rules || g.SweetsCount() != 3 {
+		t.Fatalf("rules not applied at the next round: %+v, %d sweets", g.Rules(), g.SweetsCount())
+	}
+
+	// bans removed from the config are lifted, not those of the admin API
+	l.Bans = nil
+	SetLive(l)
+	if _, ok := banned("Troll"); ok {
+		t.Fatal("config ban not lifted")
+	}
+	if _, ok := banned("Keep"); !ok {
+		t.Fatal("admin ban lifted by the config")
+	}
+}
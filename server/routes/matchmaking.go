@@ -0,0 +1,186 @@
+package routes
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/anticheat"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/store"
+)
+
+// matchInterval is how often the queue is checked for groups.
+const matchInterval = 250 * time.Millisecond
+
+// ticket is a client waiting in the matchmaking queue.
+type ticket struct {
+	c      *Client
+	name   string
+	rating float64
+	since  time.Time
+}
+
+// matchmaker groups queued players of similar rating into new rooms. A group of size
+// queued players is matched when the spread of their ratings fits in the window of every
+// one of them; the window starts at window and widens by widen per second of waiting.
+type matchmaker struct {
+	mu            sync.Mutex
+	tickets       []*ticket
+	size          int
+	window, widen float64
+	starting      sync.Mutex // held while the groups taken out of the queue move to their rooms
+}
+
+var mm = matchmaker{size: 4, window: 100, widen: 25}
+
+// SetMatchmaking sets the size of the groups and the rating window of the matchmaker.
+func SetMatchmaking(size int, window, widen float64) {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.size, mm.window, mm.widen = size, window, widen
+}
+
+// settings returns the size of the groups and the rating window.
+func (m *matchmaker) settings() (size int, window, widen float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.size, m.window, m.widen
+}
+
+// accepts returns the rating spread the ticket accepts at time now. Caller must hold m.mu.
+func (m *matchmaker) accepts(t *ticket, now time.Time) float64 {
+	return m.window + m.widen*now.Sub(t.since).Seconds()
+}
+
+// Queue errors, answered like a refused join.
+var (
+	errQueued     = &game.JoinError{Code: "already_queued", Message: "already queued"}
+	errNameQueued = &game.JoinError{Code: "name_taken", Message: "name already in the queue"}
+)
+
+// enqueue adds c to the queue under name. A name is queued once (ignoring case), the
+// game of the room would refuse the second one.
+func (m *matchmaker) enqueue(c *Client, name string) error {
+	t := &ticket{c: c, name: name, rating: store.Default.Rating(name), since: time.Now()}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, o := range m.tickets {
+		if o.c == c {
+			return errQueued
+		}
+		if strings.EqualFold(o.name, name) {
+			return errNameQueued
+		}
+	}
+	m.tickets = append(m.tickets, t)
+	c.logger().Info("queued", "as", name, "rating", int(t.rating))
+	return nil
+}
+
+// dequeue removes c from the queue, it returns false if c was not queued.
+// Once it returns, c is not being moved to a room anymore.
+func (m *matchmaker) dequeue(c *Client) bool {
+	m.mu.Lock()
+	for i, t := range m.tickets {
+		if t.c == c {
+			m.tickets = append(m.tickets[:i], m.tickets[i+1:]...)
+			m.mu.Unlock()
+			return true
+		}
+	}
+	m.mu.Unlock()
+	// c may be in a group taken out of the queue: wait until it is in its room
+	m.starting.Lock()
+	m.starting.Unlock()
+	return false
+}
+
+func (m *matchmaker) run() {
+	for range time.Tick(matchInterval) {
+		m.match(time.Now())
+	}
+}
+
+// match forms every group possible at time now and moves its players to a new room.
+// The rooms are created and joined out of m.mu, the queue stays open meanwhile.
+func (m *matchmaker) match(now time.Time) {
+	m.starting.Lock()
+	defer m.starting.Unlock()
+	for _, group := range m.groups(now) {
+		startMatch(group)
+	}
+}
+
+// groups takes out of the queue every group possible at time now.
+func (m *matchmaker) groups(now time.Time) [][]*ticket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.tickets) < m.size {
+		return nil
+	}
+	var groups [][]*ticket
+	sort.Slice(m.tickets, func(i, j int) bool { return m.tickets[i].rating < m.tickets[j].rating })
+	var left []*ticket
+	i := 0
+	for i < len(m.tickets) {
+		if i+m.size > len(m.tickets) {
+			left = append(left, m.tickets[i:]...)
+			break
+		}
+		group := m.tickets[i : i+m.size]
+		spread := group[len(group)-1].rating - group[0].rating
+		ok := true
+		for _, t := range group {
+			if spread > m.accepts(t, now) {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			left = append(left, m.tickets[i])
+			i++
+			continue
+		}
+		groups = append(groups, group)
+		i += m.size
+	}
+	m.tickets = left
+	return groups
+}
+
+// startMatch creates a room for the group and moves its clients into it.
+// Called by match, with mm.starting held.
+func startMatch(group []*ticket) {
+	r := newRoom(NewGame())
+	players := make([]map[string]interface{}, 0, len(group))
+	for _, t := range group {
+		players = append(players, map[string]interface{}{"name": t.name, "rating": t.rating})
+	}
+	for _, t := range group {
+		c := t.c
+		p, err := r.Game().Join(t.name)
+		if err != nil {
+			c.reply(joinError(err))
+			continue
+		}
+		c.mu.Lock()
+		old, oldID := c.room, c.playerID
+		c.mu.Unlock()
+		if oldID != "" {
+			old.Game().RemovePlayer(oldID)
+			anticheat.Default.Leave(old.ID + "/" + oldID)
+		}
+		old.hub.remove(c)
+		r.enter(c)
+		c.mu.Lock()
+		c.room, c.playerID, c.name = r, p.ID, p.Name
+		c.mu.Unlock()
+		old.idle()
+		c.reply(map[string]interface{}{"type": "match_found", "room": r.ID, "players": players})
+		c.reply(joinAck(r, p))
+	}
+	slog.Info("match started", "room", r.ID, "players", len(group))
+}
@@ -0,0 +1,133 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/store"
+	"github.com/gorilla/websocket"
+)
+
+func TestMatchmakerWindow(t *testing.T) {
+	setLive(t, func(l *Live) { l.RoomIdle = 0 }) // empty matched rooms are removed right away
+	now := time.Now()
+	newTicket := func(name string, rating float64, since time.Time) *ticket {
+		c := &Client{send: make(chan []byte, 16), room: getRoom(DefaultRoom)}
+		return &ticket{c: c, name: name, rating: rating, since: since}
+	}
+	m := matchmaker{size: 2, window: 100, widen: 25} // not the one of the server, which runs
+	a, b, c := newTicket("A", 1500, now), newTicket("B", 1550, now), newTicket("C", 1800, now)
+	m.tickets = []*ticket{c, a, b}
+	m.match(now)
+	if len(m.tickets) != 1 || m.tickets[0] != c {
+		t.Fatalf("expected A and B matched and C left, got %d tickets", len(m.tickets))
+	}
+	ra, _ := a.c.current()
+	rb, _ := b.c.current()
+	if ra == getRoom(DefaultRoom) || ra != rb {
+		t.Fatalf("A and B should share a new room")
+	}
+
+	// 300 points apart: too far at first, fine once C has waited long enough
+	d := newTicket("D", 1500, now)
+	m.tickets = append(m.tickets, d)
+	m.match(now)
+	if len(m.tickets) != 2 {
+		t.Fatalf("C and D should not match yet")
+	}
+	m.match(now.Add(10 * time.Second)) // window 100+25*10 = 350
+	if len(m.tickets) != 0 {
+		t.Fatalf("C and D should match after waiting")
+	}
+
+	for _, tk := range []*ticket{a, b, c, d} {
+		r, _ := tk.c.current()
+		r.hub.remove(tk.c)
+		r.idle()
+	}
+	if getRoom(ra.ID) != nil {
+		t.Fatalf("empty room %s should be removed", ra.ID)
+	}
+}
+
+func TestMatchmakerNameQueuedOnce(t *testing.T) {
+	m := matchmaker{size: 2, window: 100, widen: 25}
+	a := &Client{send: make(chan []byte, 16), room: getRoom(DefaultRoom)}
+	b := &Client{send: make(chan []byte, 16), room: getRoom(DefaultRoom)}
+	if err := m.enqueue(a, "Twin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.enqueue(a, "Other"); err != errQueued {
+		t.Fatalf("expected already_queued, got %v", err)
+	}
+	// the second one would get name_taken in the room, once matched with the first
+	if err := m.enqueue(b, "twin"); err != errNameQueued {
+		t.Fatalf("expected name_taken, got %v", err)
+	}
+	if len(m.tickets) != 1 {
+		t.Fatalf("expected a single ticket, got %d", len(m.tickets))
+	}
+	m.dequeue(a)
+	if err := m.enqueue(b, "twin"); err != nil {
+		t.Fatalf("name free again once dequeued: %v", err)
+	}
+}
+
+func TestIntegrationQueue(t *testing.T) {
+	setLive(t, func(l *Live) { l.MatchSize = 2 })
+	g := game.NewGame(5, 5, 0)
+	g.Start(100)
+	defer g.Stop()
+	useDefaultGame(t, g)
+	store.Default = store.New()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", WS)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	roomsCh := make(chan string, 2)
+	for _, name := range []string{"Q1", "Q2"} {
+		c, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer c.Close()
+		b, _ := json.Marshal(map[string]interface{}{"type": "queue", "name": name})
+		c.WriteMessage(websocket.TextMessage, b)
+		go func(c *websocket.Conn) {
+			// wait for match_found then the join_ack of the room
+			// a single deadline: after a timeout the connection cannot be read anymore
+			c.SetReadDeadline(time.Now().Add(2 * time.Second))
+			room := ""
+			for {
+				_, msg, err := c.ReadMessage()
+				if err != nil {
+					room = ""
+					break
+				}
+				var m map[string]interface{}
+				if json.Unmarshal(msg, &m) != nil {
+					continue
+				}
+				if m["type"] == "match_found" {
+					room, _ = m["room"].(string)
+				}
+				if m["type"] == "join_ack" && room != "" && m["room"] == room {
+					break
+				}
+			}
+			roomsCh <- room
+		}(c)
+	}
+	rooms := []string{<-roomsCh, <-roomsCh}
+	if rooms[0] == "" || rooms[0] != rooms[1] {
+		t.Fatalf("both players should be matched in the same room, got %v", rooms)
+	}
+}
@@ -0,0 +1,39 @@
+package routes
+
+import (
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/metrics"
+)
+
+// metrics of the network side, exposed on /metrics (the game ones are in server/game)
+var (
+	hubOverflows = metrics.NewCounter("hub_send_overflows_total", "Broadcasts not delivered because a client's send channel was full (the client is dropped).")
+	messageSize  = metrics.NewHistogramVec("ws_message_size_bytes", "Size of the WebSocket messages.", "direction",
+		[]float64{32, 64, 128, 256, 512, 1024, 2048, 4096, 8192, 16384})
+	bytesSent     = metrics.NewCounter("ws_sent_bytes_total", "Bytes sent to WebSocket clients.")
+	bytesSentRate = metrics.NewGauge("ws_sent_bytes_per_second", "Bytes sent to WebSocket clients during the last second.")
+)
+
+func init() {
+	metrics.NewGaugeFunc("ws_connected_clients", "Open WebSocket connections and SSE streams.", func() float64 {
+		adm.mu.Lock()
+		defer adm.mu.Unlock()
+		return float64(adm.total)
+	})
+	metrics.NewGaugeVecFunc("room_players", "Players in each room.", "room", func() map[string]float64 {
+		players := make(map[string]float64)
+		for _, r := range Rooms() {
+			players[r.ID] = float64(r.Game().PlayersCount())
+		}
+		return players
+	})
+	go func() {
+		last := bytesSent.Value()
+		for range time.Tick(time.Second) {
+			v := bytesSent.Value()
+			bytesSentRate.Set(float64(v - last))
+			last = v
+		}
+	}()
+}
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"log/slog"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/anticheat"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/store"
+)
+
+// recordRound updates the persistent player profiles at each game_over of room.
+func recordRound(room string, scores []game.Score) {
+	results := make([]store.Result, 0, len(scores))
+	for _, s := range scores {
+		if anticheat.Default.Shadowed(s.Name) {
+			continue // shadow-flagged players count for nothing, nor against anyone
+		}
+		results = append(results, store.Result{Name: s.Name, Score: s.Score})
+	}
+	if err := store.Default.RecordRound(room, results); err != nil {
+		slog.Error("saving profiles", "room", room, "err", err)
+	}
+}
+
+// statsMessage answers a "stats" request with the profile of name.
+func statsMessage(name string) map[string]interface{} {
+	p, ok := store.Default.Get(name)
+	if !ok {
+		return map[string]interface{}{"type": "error", "message": "unknown player"}
+	}
+	return map[string]interface{}{"type": "stats", "profile": p}
+}
@@ -0,0 +1,106 @@
+package routes
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Inbound message limits, per client (rate and burst: see SetLive).
+var (
+	MaxMessageSize int64 = 4096 // bytes, larger messages close the connection
+	MsgRate              = 20.0 // messages per second (a move per tick at 20 ticks/s)
+	MsgBurst             = 40.0 // messages allowed at once
+)
+
+// Escalation of the responses to a client going over its limits. A strike is a
+// message dropped by the rate limit or a move dropped because the player's queue is full.
+const (
+	throttleAfter    = 20               // strikes before throttling (the first strike only warns)
+	kickAfter        = 100              // strikes before kicking
+	throttleDuration = 10 * time.Second // throttled clients get half the rate
+	strikesForgotten = 10 * time.Second // strikes reset after this long without one
+)
+
+// limiter is the token bucket and the strikes of a client, used by one goroutine at a time.
+type limiter struct {
+	tokens     float64
+	last       time.Time
+	strikes    int
+	lastStrike time.Time
+	throttled  time.Time // throttled until
+}
+
+func newLimiter(now time.Time) *limiter {
+	liveMu.RLock()
+	defer liveMu.RUnlock()
+	return &limiter{tokens: MsgBurst, last: now}
+}
+
+// allow takes a token for a message received at now.
+func (l *limiter) allow(now time.Time) bool {
+	liveMu.RLock()
+	rate, burst := MsgRate, MsgBurst
+	liveMu.RUnlock()
+	if now.Before(l.throttled) {
+		rate /= 2
+	}
+	l.tokens += now.Sub(l.last).Seconds() * rate
+	if l.tokens > burst {
+		l.tokens = burst
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// strike records an abuse at now and returns the response: "warning" the first time,
+// "throttled" when the throttle starts, "kicked" at kickAfter, "" otherwise.
+func (l *limiter) strike(now time.Time) string {
+	if now.Sub(l.lastStrike) > strikesForgotten {
+		l.strikes = 0
+	}
+	l.strikes++
+	l.lastStrike = now
+	switch l.strikes {
+	case 1:
+		return "warning"
+	case throttleAfter:
+		l.throttled = now.Add(throttleDuration)
+		return "throttled"
+	case kickAfter:
+		return "kicked"
+	}
+	return ""
+}
+
+// abuse applies a strike to c. It returns true if the client has been kicked.
+func (c *Client) abuse(l *limiter, reason string) bool {
+	action := l.strike(time.Now())
+	if action == "" {
+		return false
+	}
+	if action == "kicked" {
+		c.kick(reason)
+		return true
+	}
+	c.reply(map[string]interface{}{"type": "event", "event": action, "reason": reason})
+	return false
+}
+
+// kick tells the client why it is disconnected; the caller must then end the connection.
+func (c *Client) kick(reason string) {
+	c.reply(map[string]interface{}{"type": "event", "event": "kicked", "reason": reason})
+	// sent by writePump after the pending messages, when readPump returns
+	text := "kicked: " + reason
+	if len(text) > 123 {
+		text = strings.ToValidUTF8(text[:123], "") // a close frame carries at most 125 bytes
+	}
+	c.mu.Lock()
+	c.closing = websocket.FormatCloseMessage(websocket.ClosePolicyViolation, text)
+	c.mu.Unlock()
+}
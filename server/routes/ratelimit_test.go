@@ -0,0 +1,87 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+	"github.com/gorilla/websocket"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Now()
+	l := newLimiter(now)
+	for i := 0; i < int(MsgBurst); i++ {
+		if !l.allow(now) {
+			t.Fatalf("message %d of the burst refused", i)
+		}
+	}
+	if l.allow(now) {
+		t.Fatalf("message over the burst accepted")
+	}
+	// refills at MsgRate
+	if !l.allow(now.Add(time.Second / time.Duration(MsgRate))) {
+		t.Fatalf("bucket did not refill")
+	}
+
+	actions := map[string]int{}
+	for i := 1; i <= kickAfter; i++ {
+		if a := l.strike(now); a != "" {
+			actions[a] = i
+		}
+	}
+	if actions["warning"] != 1 || actions["throttled"] != throttleAfter || actions["kicked"] != kickAfter {
+		t.Fatalf("unexpected escalation %v", actions)
+	}
+	// strikes are forgotten after a quiet period
+	if a := l.strike(now.Add(strikesForgotten + time.Second)); a != "warning" {
+		t.Fatalf("expected a fresh warning, got %q", a)
+	}
+}
+
+func TestFloodingClientIsKicked(t *testing.T) {
+	g := game.NewGame(5, 5, 0)
+	g.Start(100)
+	defer g.Stop()
+	useDefaultGame(t, g)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", WS)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+	c, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	b, _ := json.Marshal(map[string]interface{}{"type": "move", "dir": "up"})
+	for i := 0; i < int(MsgBurst)+kickAfter+10; i++ {
+		if c.WriteMessage(websocket.TextMessage, b) != nil {
+			break // already kicked
+		}
+	}
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	warned := false
+	for {
+		_, msg, err := c.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+				t.Fatalf("expected a policy violation close, got %v", err)
+			}
+			break
+		}
+		var m map[string]interface{}
+		if json.Unmarshal(msg, &m) == nil && m["event"] == "warning" {
+			warned = true
+		}
+	}
+	if !warned {
+		t.Fatalf("expected a warning before the kick")
+	}
+}
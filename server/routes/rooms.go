@@ -0,0 +1,180 @@
+package routes
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+)
+
+// DefaultRoom is the ID of the room every client plays in.
+const DefaultRoom = "default"
+
+// Room is a game instance and the clients playing in it.
+type Room struct {
+	ID   string
+	game *game.Game    // nil for the default room, which follows game.Default
+	hub  *Hub          // clients of the room
+	done chan struct{} // closed when the room is removed (nil for the default room)
+	// hibernation (see hibernate.go)
+	mu        sync.Mutex // serializes clients entering and the room going to sleep
+	sleeping  bool
+	idleSince time.Time
+}
+
+// Game returns the game of the room.
+func (r *Room) Game() *game.Game {
+	if r.game == nil {
+		defaultMu.RLock()
+		defer defaultMu.RUnlock()
+		return game.Default // replaced by SetDefaultGame, always read it
+	}
+	return r.game
+}
+
+// Settings of the games of the rooms (see the config package), set by main before serving.
+var (
+	GridW, GridH  = 10, 10
+	TickRate      = 20
+	RoomRules     = game.DefaultRules
+	RoomWalls     []game.Pos
+	RoomSpawns    []game.Pos
+	RoomPlacement game.SweetPlacement
+)
+
+// NewGame creates a game with the room settings: grid, rules and map.
+// The sweets of the first round already follow the walls and the placement.
+func NewGame() *game.Game {
+	liveMu.RLock()
+	rules := RoomRules // changed by SetLive
+	liveMu.RUnlock()
+	g := game.NewGame(GridW, GridH, 0)
+	// checked by the config, and no player stands under a wall yet
+	g.SetWalls(RoomWalls)
+	g.SetSpawnPoints(RoomSpawns)
+	g.SetSweetPlacement(RoomPlacement)
+	g.SetRules(rules)
+	g.SetTPS(TickRate)
+	g.Restart() // places the sweets
+	return g
+}
+
+var (
+	roomsMu sync.Mutex
+	rooms   = map[string]*Room{DefaultRoom: {ID: DefaultRoom, hub: &h}}
+	roomSeq int // numbering of the matched rooms
+)
+
+// newRoom registers a room for game g and starts it: hub, forwarders and game loop.
+// Used by the matchmaker, the room is removed once empty (see hibernate.go).
+func newRoom(g *game.Game) *Room {
+	done := make(chan struct{})
+	r := &Room{game: g, hub: newHub(done), done: done}
+	roomsMu.Lock()
+	roomSeq++
+	r.ID = fmt.Sprintf("m-%d", roomSeq)
+	rooms[r.ID] = r
+	roomsMu.Unlock()
+
+	go r.hub.run()
+	go forward(g.StateBroadcast, r.hub, done)
+	go forward(g.EventBroadcast, r.hub, done)
+	g.OnRoundEnd(func(scores []game.Score) { recordRound(r.ID, scores) })
+	g.SetAdaptiveTPS(MinTPS)
+	g.SetLogContext("room", r.ID)
+	g.SetAutoPause(true)
+	g.Start(TickRate)
+	slog.Info("room created", "room", r.ID)
+	return r
+}
+
+// forward sends the messages of a game channel to the hub until done is closed.
+func forward(ch chan []byte, hub *Hub, done chan struct{}) {
+	for {
+		select {
+		case b := <-ch:
+			select {
+			case hub.broadcast <- b:
+			case <-done:
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// defaultDone stops the forwarders of the game bound to the default room.
+var defaultDone chan struct{}
+
+// defaultMu guards game.Default, that SetDefaultGame replaces while clients still read it.
+var defaultMu sync.RWMutex
+
+// bindDefault makes g the game of the default room: its messages go to the room's hub.
+func bindDefault(g *game.Game) {
+	if defaultDone != nil {
+		close(defaultDone)
+	}
+	defaultDone = make(chan struct{})
+	g.SetLogContext("room", DefaultRoom)
+	g.SetAutoPause(true) // no tick nor state while nobody plays
+	// update persistent profiles at each game_over
+	g.OnRoundEnd(func(scores []game.Score) { recordRound(DefaultRoom, scores) })
+	// forward game state and events (collected etc.) to hub broadcast
+	go forward(g.StateBroadcast, &h, defaultDone)
+	go forward(g.EventBroadcast, &h, defaultDone)
+}
+
+// SetDefaultGame replaces game.Default, e.g. by a game with the configured grid.
+// Call it before serving: the players of the old game are not moved.
+func SetDefaultGame(g *game.Game) {
+	r := getRoom(DefaultRoom)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defaultMu.Lock()
+	old := game.Default
+	game.Default = g
+	defaultMu.Unlock()
+	running := old.Running()
+	old.Stop()
+	bindDefault(g)
+	if running && !g.Running() {
+		g.Start(TickRate)
+	}
+}
+
+// close stops and removes a matched room.
+func (r *Room) close() {
+	roomsMu.Lock()
+	if rooms[r.ID] != r {
+		roomsMu.Unlock()
+		return // already closed
+	}
+	delete(rooms, r.ID)
+	roomsMu.Unlock()
+	r.game.Stop()
+	close(r.done)
+	slog.Info("room closed", "room", r.ID)
+}
+
+// getRoom returns the room with this ID, nil if it does not exist.
+func getRoom(id string) *Room {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+	return rooms[id]
+}
+
+// Rooms returns every room sorted by ID.
+func Rooms() []*Room {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+	list := make([]*Room, 0, len(rooms))
+	for _, r := range rooms {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
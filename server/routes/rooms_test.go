@@ -0,0 +1,114 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+	"github.com/gorilla/websocket"
+)
+
+// useDefaultGame makes g the game of the default room through SetDefaultGame, until the
+// end of the test. The game put back is woken up by the next client, as after hibernation.
+func useDefaultGame(t *testing.T, g *game.Game) {
+	old := getRoom(DefaultRoom).Game()
+	SetDefaultGame(g)
+	t.Cleanup(func() { SetDefaultGame(old) })
+}
+
+func TestConfiguredDefaultGame(t *testing.T) {
+	defer func(w, h, tps int) { GridW, GridH, TickRate = w, h, tps }(GridW, GridH, TickRate)
+	GridW, GridH, TickRate = 7, 6, 50
+	rules := game.Rules{Sweets: 3, MaxMoves: 1, Intermission: time.Second}
+	setLive(t, func(l *Live) { l.Rules = rules })
+
+	g := NewGame()
+	defer g.Stop()
+	if g.W != 7 || g.H != 6 || g.SweetsCount() != 3 || g.Rules() != rules {
+		t.Fatalf("game not built from the settings: %dx%d, %d sweets, %+v", g.W, g.H, g.SweetsCount(), g.Rules())
+	}
+	room := getRoom(DefaultRoom)
+	room.idle() // no client left from other tests
+	useDefaultGame(t, g)
+	if game.Default != g || g.Running() {
+		t.Fatal("the sleeping default room should get the new game, not started")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", WS)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	c, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.WriteJSON(map[string]interface{}{"type": "join", "name": "Configured"})
+	ack := readType(t, c, "join_ack")
+	if grid := ack["grid"].(map[string]interface{}); grid["w"] != 7.0 || grid["h"] != 6.0 {
+		t.Fatalf("unexpected grid %v", grid)
+	}
+	// the states of the new game reach the clients of the room
+	if st := readType(t, c, "state"); st["tick"].(float64) < 1 {
+		t.Fatalf("unexpected state %v", st)
+	}
+	if s := g.LoopStats(); s.TPS != 50 {
+		t.Fatalf("expected the room to tick at 50/s, got %d", s.TPS)
+	}
+}
+
+func TestMatchedRoomMap(t *testing.T) {
+	defer func(w, s []game.Pos, sp game.SweetPlacement) {
+		RoomWalls, RoomSpawns, RoomPlacement = w, s, sp
+	}(RoomWalls, RoomSpawns, RoomPlacement)
+	RoomWalls = []game.Pos{{X: 4, Y: 4}, {X: 4, Y: 5}}
+	RoomSpawns = []game.Pos{{X: 0, Y: 0}, {X: 4, Y: 4}} // the second one is a wall
+	RoomPlacement = game.SweetPlacement{Strategy: game.PlacementSymmetric, MinSpacing: 1}
+	setLive(t, func(l *Live) { l.AdminToken = "s3cret"; l.Rules.Sweets = 6 })
+
+	room := newRoom(NewGame())
+	defer room.close()
+	g := room.Game()
+	if g.SweetPlacement() != RoomPlacement || len(g.Walls()) != 2 || g.SweetsCount() != 6 {
+		t.Fatalf("room not built from the map settings: %+v, walls %v, %d sweets", g.SweetPlacement(), g.Walls(), g.SweetsCount())
+	}
+	p := g.AddPlayer("Spawned")
+	if p.X != 0 || p.Y != 0 {
+		t.Fatalf("expected the player on the spawn point 0,0, got %d,%d", p.X, p.Y)
+	}
+	g.RemovePlayer(p.ID)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/admin/rooms/{id}", AdminRoom)
+	mux.HandleFunc("POST /api/admin/rooms/{id}/placement", AdminPlacement)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	admin := func(method, path, body string, v interface{}) int {
+		req, _ := http.NewRequest(method, srv.URL+"/api/admin/rooms/"+room.ID+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		json.NewDecoder(resp.Body).Decode(v)
+		return resp.StatusCode
+	}
+	var report struct {
+		Placement game.SweetPlacement `json:"placement"`
+		Walls     []game.Pos          `json:"walls"`
+	}
+	if code := admin("GET", "", "", &report); code != http.StatusOK || report.Placement.Strategy != game.PlacementSymmetric || len(report.Walls) != 2 || report.Walls[0] != (game.Pos{X: 4, Y: 4}) {
+		t.Fatalf("report: %d %+v", code, report)
+	}
+	if code := admin("POST", "/placement", `{"strategy":"clustered","clusters":2}`, &report); code != http.StatusOK || report.Placement.Strategy != game.PlacementClustered || report.Placement.Clusters != 2 {
+		t.Fatalf("placement: %d %+v", code, report)
+	}
+	if code := admin("POST", "/placement", `{"strategy":"spiral"}`, &report); code != http.StatusBadRequest {
+		t.Fatalf("unknown strategy accepted: %d", code)
+	}
+}
@@ -5,7 +5,11 @@ import (
 	"net/http"
 )
 
+// Root answers "OK" on / (the probes are /healthz and /readyz, see health.go).
 func Root(res http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/" {
+		http.NotFound(res, req)
+		return
+	}
 	fmt.Fprintln(res, "OK")
-	fmt.Println("root")
 }
@@ -0,0 +1,190 @@
+package routes
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/metrics"
+)
+
+// Server-Sent Events: a fallback transport for the clients whose proxy breaks WebSockets.
+// The client reads the messages of its room from GET /api/rooms/{id}/events and sends
+// its own with POST /api/rooms/{id}/input. Behind both is a Client, as for /ws: same
+// messages, same limits, and the player leaves when the stream ends.
+
+// SSEKeepAlive is how often an idle stream gets a comment, so that proxies keep it open.
+var SSEKeepAlive = 15 * time.Second
+
+// sseSession is a client of the events stream and the state of its inputs.
+type sseSession struct {
+	c  *Client
+	mu sync.Mutex // one input at a time, as readPump
+	in *inbound
+}
+
+var (
+	sessionsMu sync.Mutex
+	sessions   = map[string]*sseSession{} // by session ID
+)
+
+func init() {
+	metrics.NewGaugeFunc("sse_connected_clients", "Open Server-Sent Events streams.", func() float64 {
+		sessionsMu.Lock()
+		defer sessionsMu.Unlock()
+		return float64(len(sessions))
+	})
+}
+
+// newSessionID returns a random session ID: whoever knows it plays as the client.
+func newSessionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Events serves GET /api/rooms/{id}/events: the state and the events of the room as
+// Server-Sent Events, each data line holding the JSON message a WebSocket client would
+// get. The first one, {"type":"session","session":...}, gives the session of Input.
+// Same token, origin and admission checks as /ws, and the same room: the stream enters
+// the default room, only the matchmaker moves clients into the matched rooms.
+func Events(w http.ResponseWriter, r *http.Request) {
+	room := getRoom(r.PathValue("id"))
+	if room == nil {
+		writeError(w, http.StatusNotFound, "unknown room")
+		return
+	}
+	if room.ID != DefaultRoom {
+		writeError(w, http.StatusForbidden, "matched rooms are entered through the queue")
+		return
+	}
+	if !checkOrigin(r) {
+		writeError(w, http.StatusForbidden, "origin not allowed")
+		return
+	}
+	account, err := authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if _, ok := banned(account); account != "" && ok {
+		http.Error(w, "banned", http.StatusForbidden)
+		return
+	}
+	if roomFull(room) {
+		refuse(w, http.StatusServiceUnavailable, "room full")
+		return
+	}
+	ip := clientIP(r)
+	if status, msg := adm.admit(ip); status != 0 {
+		slog.Warn("connection refused", "remote", ip, "status", status, "reason", msg)
+		refuse(w, status, msg)
+		return
+	}
+	defer adm.release(ip)
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx would hold the messages back
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		slog.Warn("events stream not supported", "remote", ip, "err", err)
+		return
+	}
+
+	id := newSessionID()
+	c := &Client{send: make(chan []byte, SendBuffer), room: room, account: account, remote: r.RemoteAddr}
+	sessionsMu.Lock()
+	sessions[id] = &sseSession{c: c, in: newInbound()}
+	sessionsMu.Unlock()
+	defer func() {
+		sessionsMu.Lock()
+		delete(sessions, id)
+		sessionsMu.Unlock()
+	}()
+	c.logger().Info("client connected", "transport", "sse")
+	// the session comes first, before any broadcast
+	c.reply(map[string]interface{}{"type": "session", "session": id, "room": room.ID})
+	room.enter(c) // wakes the room up if it sleeps
+	defer c.leave()
+
+	keepAlive := time.NewTicker(SSEKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		var b []byte
+		select {
+		case msg, ok := <-c.send:
+			if !ok {
+				return // kicked or too slow, the pending messages have been sent
+			}
+			b = append(append([]byte("data: "), msg...), "\n\n"...) // the messages are one line of JSON
+			messageSize.With("out").Observe(float64(len(msg)))
+			bytesSent.Add(uint64(len(msg)))
+		case <-keepAlive.C:
+			b = []byte(": keep-alive\n\n")
+		case <-r.Context().Done():
+			return // the client went away
+		}
+		rc.SetWriteDeadline(time.Now().Add(writeWait)) // a stuck peer must not block us forever
+		_, err := w.Write(b)
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			c.logger().Warn("write error", "err", err)
+			return
+		}
+	}
+}
+
+// Input serves POST /api/rooms/{id}/input: a message of an SSE client, the same as
+// over a WebSocket ({"type":"join","name":"Bob"}, {"type":"move","dir":"up"}...).
+// The session comes from the X-Session header, or the session query parameter.
+// The answers (join_ack, errors) come through the stream: the request gets 202.
+func Input(w http.ResponseWriter, r *http.Request) {
+	if !checkOrigin(r) {
+		writeError(w, http.StatusForbidden, "origin not allowed")
+		return
+	}
+	id := r.Header.Get("X-Session")
+	if id == "" {
+		id = r.URL.Query().Get("session")
+	}
+	sessionsMu.Lock()
+	s := sessions[id]
+	sessionsMu.Unlock()
+	if s == nil {
+		writeError(w, http.StatusNotFound, "unknown session")
+		return
+	}
+	// the matchmaker may have moved the client, its inputs follow it
+	if room, _ := s.c.current(); room.ID != r.PathValue("id") {
+		writeError(w, http.StatusConflict, "session is in room "+room.ID)
+		return
+	}
+	message, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxMessageSize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, "message too large")
+		return
+	} else if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+	s.mu.Lock()
+	ok := s.c.handle(s.in, message)
+	s.mu.Unlock()
+	if !ok {
+		// kicked: the stream sends the kicked event, then ends and the player leaves
+		room, _ := s.c.current()
+		room.hub.remove(s.c)
+		s.c.closeSend()
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,124 @@
+package routes
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+)
+
+func TestSSE(t *testing.T) {
+	g := game.NewGame(6, 6, 2)
+	defer g.Stop()
+	getRoom(DefaultRoom).idle() // no client left from other tests
+	useDefaultGame(t, g)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/rooms/{id}/events", Events)
+	mux.HandleFunc("POST /api/rooms/{id}/input", Input)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	if resp, _ := http.Get(srv.URL + "/api/rooms/nope/events"); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown room: expected 404, got %d", resp.StatusCode)
+	}
+	// matched rooms are for the players the matchmaker puts there
+	matched := newRoom(NewGame())
+	resp, err := http.Get(srv.URL + "/api/rooms/" + matched.ID + "/events")
+	matched.close()
+	if err != nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("matched room: expected 403, got %v %v", resp, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/api/rooms/default/events", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	stream := bufio.NewReader(resp.Body)
+	next := func(typ string) map[string]interface{} {
+		for {
+			line, err := stream.ReadString('\n')
+			if err != nil {
+				t.Fatalf("no %s message: %v", typ, err)
+			}
+			data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: ")
+			if !ok {
+				continue
+			}
+			var m map[string]interface{}
+			if err := json.Unmarshal([]byte(data), &m); err != nil {
+				t.Fatalf("invalid data line %q: %v", line, err)
+			}
+			if m["type"] == typ {
+				return m
+			}
+		}
+	}
+	session := next("session")["session"].(string)
+	post := func(room, session string, msg map[string]interface{}) int {
+		b, _ := json.Marshal(msg)
+		req, _ := http.NewRequest("POST", srv.URL+"/api/rooms/"+room+"/input", strings.NewReader(string(b)))
+		req.Header.Set("X-Session", session)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post("default", session, map[string]interface{}{"type": "join", "name": "Streamer"}); code != http.StatusAccepted {
+		t.Fatalf("join: expected 202, got %d", code)
+	}
+	ack := next("join_ack")
+	id := ack["id"].(string)
+	if ack["room"] != DefaultRoom || g.PlayersCount() != 1 {
+		t.Fatalf("unexpected join_ack %v", ack)
+	}
+	// the states of the hub reach the stream
+	x := ack["pos"].(map[string]interface{})["x"].(float64)
+	dir := "right"
+	if x == 5 {
+		dir = "left"
+	}
+	post("default", session, map[string]interface{}{"type": "move", "dir": dir})
+	for moved := false; !moved; {
+		for _, p := range next("state")["players"].([]interface{}) {
+			p := p.(map[string]interface{})
+			moved = moved || (p["id"] == id && p["x"].(float64) != x)
+		}
+	}
+
+	if code := post("default", "bogus", map[string]interface{}{"type": "move", "dir": "up"}); code != http.StatusNotFound {
+		t.Fatalf("unknown session: expected 404, got %d", code)
+	}
+	if code := post("m-0", session, map[string]interface{}{"type": "move", "dir": "up"}); code != http.StatusConflict {
+		t.Fatalf("other room: expected 409, got %d", code)
+	}
+
+	// closing the stream ends the session, as closing a WebSocket
+	resp.Body.Close()
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for g.PlayersCount() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("player still in the game after the stream closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if code := post("default", session, map[string]interface{}{"type": "move", "dir": "up"}); code != http.StatusNotFound {
+		t.Fatalf("ended session: expected 404, got %d", code)
+	}
+}
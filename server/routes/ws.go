@@ -2,23 +2,39 @@ package routes
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/anticheat"
 	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/logging"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/store"
 )
 
+// SendBuffer is the number of messages waiting to be written to a client,
+// beyond which broadcasts to it are dropped.
+var SendBuffer = 256
+
 var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool { return true },
+	CheckOrigin: checkOrigin, // see admission.go
 }
 
 // Client represents a websocket client connection.
 type Client struct {
-	conn     *websocket.Conn
-	send     chan []byte
+	conn    *websocket.Conn
+	send    chan []byte
+	account string // account of the auth token, "" if anonymous
+	remote  string // remote address, for the logs
+	// protects below, the matchmaker moves clients between rooms
+	mu       sync.Mutex
+	closed   bool  // send has been closed
+	closing  []byte // close frame sent by writePump once send is drained (e.g. kicked)
+	room     *Room // room the client is in
 	playerID string
+	name     string
 }
 
 // Hub maintains the set of active clients and broadcasts messages to them.
@@ -27,6 +43,7 @@ type Hub struct {
 	broadcast  chan []byte // messages to broadcast to all clients
 	register   chan *Client // queue for registering new clients
 	unregister chan *Client // queue for unregistering clients
+	done       chan struct{} // closed to stop the hub (nil: runs forever)
 	mu         sync.Mutex
 }
 
@@ -37,20 +54,20 @@ var h = Hub{
 	unregister: make(chan *Client),
 }
 
+func newHub(done chan struct{}) *Hub {
+	return &Hub{
+		clients:    make(map[*Client]bool),
+		broadcast:  make(chan []byte),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		done:       done,
+	}
+}
+
 func init() {
 	go h.run()
-	// forward game state to hub broadcast
-	go func() {
-		for b := range game.Default.StateBroadcast {
-			h.broadcast <- b
-		}
-	}()
-	// forward game events to hub broadcast (collected etc.)
-	go func() {
-		for b := range game.Default.EventBroadcast {
-			h.broadcast <- b
-		}
-	}()
+	bindDefault(game.Default)
+	go mm.run()
 }
 
 // Manage the hub: register/unregister clients and broadcast messages.
@@ -59,110 +76,387 @@ func (hub *Hub) run() {
 		select {
 		// New client registration, add his connection
 		case c := <-hub.register:
-			hub.mu.Lock()
-			hub.clients[c] = true
-			hub.mu.Unlock()
-			log.Println("[WS] client registered")
+			hub.add(c)
+			c.logger().Debug("client registered")
 		// Client unregistration, delete his connection
 		case c := <-hub.unregister:
 			hub.mu.Lock()
 			if _, ok := hub.clients[c]; ok {
 				delete(hub.clients, c)
-				close(c.send)
+				c.closeSend()
 			}
 			hub.mu.Unlock()
-			log.Println("[WS] client unregistered")
+			c.logger().Debug("client unregistered")
 		// Broadcast message to all clients
 		case msg := <-hub.broadcast:
 			hub.mu.Lock()
 			for c := range hub.clients {
-				select {
-				case c.send <- msg:
-				default:
-					close(c.send)
+				if !c.trySend(msg) {
+					hubOverflows.Inc()
+					c.closeSend()
 					delete(hub.clients, c)
 				}
 			}
 			hub.mu.Unlock()
+		case <-hub.done:
+			return
 		}
 	}
 }
 
+// add registers c right away (register goes through run).
+func (hub *Hub) add(c *Client) {
+	hub.mu.Lock()
+	hub.clients[c] = true
+	hub.mu.Unlock()
+}
+
+// remove detaches c from the hub without closing it, to move it to another room.
+func (hub *Hub) remove(c *Client) {
+	hub.mu.Lock()
+	delete(hub.clients, c)
+	hub.mu.Unlock()
+}
+
+// count returns the number of clients in the hub.
+func (hub *Hub) count() int {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	return len(hub.clients)
+}
+
+// trySend queues msg for the client without blocking. It returns false if the
+// client is closed or too slow to keep up.
+func (c *Client) trySend(msg []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	select {
+	case c.send <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// closeSend closes the send channel once, which ends writePump.
+func (c *Client) closeSend() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.send)
+	}
+}
+
+// reply sends v as JSON to this client only, through writePump
+// (only one goroutine may write on the connection).
+func (c *Client) reply(v interface{}) {
+	b, _ := json.Marshal(v)
+	c.trySend(b)
+}
+
+// current returns the room of the client and its player ID in that room.
+func (c *Client) current() (*Room, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.room, c.playerID
+}
+
+// logger returns a logger carrying the context of the connection: remote address,
+// account, room and player. Built at each call, the client moves between rooms.
+func (c *Client) logger() *slog.Logger {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	args := []any{"remote", c.remote}
+	if c.account != "" {
+		args = append(args, "account", c.account)
+	}
+	if c.room != nil {
+		args = append(args, "room", c.room.ID)
+	}
+	if c.playerID != "" {
+		args = append(args, "player", c.playerID, "name", c.name)
+	}
+	return slog.With(args...)
+}
+
+// LogSample is the share of the high-frequency messages that are logged (moves, dropped
+// messages): 1 in LogSample per connection, 0 for none.
+var LogSample = 100
+
 // readPump reads messages from the websocket connection.
 func (c *Client) readPump() {
-	defer func() {
-		if c.playerID != "" {
-			game.Default.RemovePlayer(c.playerID)
-		}
-		h.unregister <- c
-		c.conn.Close()
-	}()
+	defer c.leave()
+	c.conn.SetReadLimit(MaxMessageSize) // larger messages end the connection
+	in := newInbound()
 	for {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
-			log.Println("[WS] read error:", err)
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				c.logger().Warn("read error", "err", err)
+			}
 			break
 		}
-		log.Println("[WS] recv:", string(message))
-		// parse JSON message
-		var m map[string]interface{}
-		// decode json
-		if err := json.Unmarshal(message, &m); err != nil {
-			log.Println("[WS] invalid json:", err)
-			continue
-		}
-		typeStr, _ := m["type"].(string)
-		switch typeStr {
-		case "join":
-			name, _ := m["name"].(string)
-			p := game.Default.AddPlayer(name)
-			if p == nil {
-				resp := map[string]interface{}{"type": "error", "message": "unable to add player"}
-				b, _ := json.Marshal(resp)
-				c.conn.WriteMessage(websocket.TextMessage, b)
-				continue
+		if !c.handle(in, message) {
+			break // kicked
+		}
+	}
+}
+
+// leave removes the player of c and the client from its room, at the end of the connection.
+func (c *Client) leave() {
+	mm.dequeue(c)
+	anticheat.Default.Forget(c.cheatKey())
+	room, playerID := c.current()
+	if playerID != "" {
+		room.Game().RemovePlayer(playerID)
+		anticheat.Default.Leave(room.ID + "/" + playerID)
+	}
+	// synchronous so that idle sees the room without us
+	room.hub.remove(c)
+	c.closeSend() // writePump sends what is left, then closes the connection
+	c.logger().Info("client disconnected")
+	room.idle() // hibernates once nobody is left (see hibernate.go)
+}
+
+// cheatKey is the anti-cheat key of the client itself, for its moves without a player.
+func (c *Client) cheatKey() string {
+	return "conn/" + c.remote
+}
+
+// inbound is the state of the messages received from a client: rate limit and log sampling.
+type inbound struct {
+	lim          *limiter // see ratelimit.go
+	moves, drops *logging.Sampler
+}
+
+func newInbound() *inbound {
+	return &inbound{lim: newLimiter(time.Now()), moves: logging.NewSampler(LogSample), drops: logging.NewSampler(LogSample)}
+}
+
+// handle applies a message of c (join, move...), received over its WebSocket or its
+// SSE session (see sse.go). It returns false once c is kicked: the caller ends the connection.
+func (c *Client) handle(in *inbound, message []byte) bool {
+	messageSize.With("in").Observe(float64(len(message)))
+	if !in.lim.allow(time.Now()) {
+		if ok, n := in.drops.Sample(); ok {
+			c.logger().Warn("message dropped", "reason", "rate_limited", "dropped", n)
+		}
+		if c.abuse(in.lim, "rate_limited") {
+			c.logger().Warn("kicked", "reason", "rate_limited")
+			return false
+		}
+		return true // dropped
+	}
+	// parse JSON message
+	var m map[string]interface{}
+	// decode json
+	if err := json.Unmarshal(message, &m); err != nil {
+		c.logger().Debug("invalid json", "err", err)
+		return true
+	}
+	typeStr, _ := m["type"].(string)
+	if typeStr != "move" {
+		c.logger().Debug("recv", "type", typeStr, "data", string(message))
+	} else if ok, n := in.moves.Sample(); ok {
+		// moves are too frequent to log them all
+		c.logger().Debug("recv", "type", typeStr, "data", string(message), "moves", n)
+	}
+	room, playerID := c.current()
+	switch typeStr {
+	case "join":
+		name, _ := m["name"].(string)
+		name, ok := c.playerName(name)
+		if !ok {
+			c.reply(map[string]interface{}{"type": "error", "code": "login_required", "message": "name belongs to an account, log in first"})
+			return true
+		}
+		if b, ok := banned(name); ok {
+			c.reply(banError(b))
+			return true
+		}
+		// the cap is checked before the upgrade too, but several clients may race for the last slot
+		if roomFull(room) {
+			c.reply(map[string]interface{}{"type": "error", "code": "room_full", "message": "room is full"})
+			return true
+		}
+		p, err := room.Game().Join(name)
+		if err != nil {
+			c.reply(joinError(err))
+			return true
+		}
+		c.mu.Lock()
+		c.playerID = p.ID
+		c.name = p.Name
+		c.mu.Unlock()
+		c.logger().Info("player joined")
+		c.reply(joinAck(room, p))
+	case "move":
+		if playerID == "" {
+			if anticheat.Default.NotInGame(c.cheatKey(), c.name, time.Now()) == anticheat.Kick {
+				c.logger().Warn("kicked", "reason", "cheating")
+				c.kick("cheating")
+				return false
+			}
+			c.reply(map[string]interface{}{"type": "error", "message": "not joined"})
+			return true
+		}
+		if room.Game().Paused() {
+			return true // the game would drop it anyway, the client got the "paused" event
+		}
+		dir, _ := m["dir"].(string)
+		// anti-cheat: impossible inputs are dropped, repeated offenders kicked (see server/anticheat)
+		_, hasX := m["x"]
+		_, hasY := m["y"]
+		switch anticheat.Default.Move(room.ID+"/"+playerID, c.name, dir, hasX || hasY, time.Now()) {
+		case anticheat.Reject:
+			return true
+		case anticheat.Kick:
+			c.logger().Warn("kicked", "reason", "cheating")
+			c.kick("cheating")
+			return false
+		}
+		cmd := game.Command{PlayerID: playerID, Type: "move", Dir: dir}
+		if !room.Game().PushCommand(cmd) {
+			// more moves than the game can apply: only this player loses some
+			if ok, n := in.drops.Sample(); ok {
+				c.logger().Warn("message dropped", "reason", "queue_full", "dropped", n)
 			}
-			c.playerID = p.ID
-			ack := map[string]interface{}{"type": "join_ack", "id": p.ID, "pos": map[string]int{"x": p.X, "y": p.Y}, "grid": map[string]int{"w": game.Default.W, "h": game.Default.H}}
-			b, _ := json.Marshal(ack)
-			c.conn.WriteMessage(websocket.TextMessage, b)
-		case "move":
-			if c.playerID == "" {
-				resp := map[string]interface{}{"type": "error", "message": "not joined"}
-				b, _ := json.Marshal(resp)
-				c.conn.WriteMessage(websocket.TextMessage, b)
-				continue
+			if c.abuse(in.lim, "queue_full") {
+				c.logger().Warn("kicked", "reason", "queue_full")
+				return false
 			}
-			dir, _ := m["dir"].(string)
-			cmd := game.Command{PlayerID: c.playerID, Type: "move", Dir: dir}
-			game.Default.PushCommand(cmd)
-		default:
-			// ignore unknown types for now
 		}
+	case "stats":
+		// profile of the given player, or our own
+		name, _ := m["name"].(string)
+		if name == "" {
+			name = c.name
+		}
+		c.reply(statsMessage(name))
+	case "queue":
+		// matchmaking: wait for players of a similar rating (see matchmaking.go)
+		name, _ := m["name"].(string)
+		if name == "" {
+			name = c.name
+		}
+		name, ok := c.playerName(name)
+		if !ok {
+			c.reply(map[string]interface{}{"type": "error", "code": "login_required", "message": "name belongs to an account, log in first"})
+			return true
+		}
+		name = game.NormalizeName(name)
+		if err := game.ValidateName(name); err != nil {
+			c.reply(joinError(err))
+			return true
+		}
+		if b, ok := banned(name); ok {
+			c.reply(banError(b))
+			return true
+		}
+		if err := mm.enqueue(c, name); err != nil {
+			c.reply(joinError(err))
+			return true
+		}
+		size, _, _ := mm.settings()
+		c.reply(map[string]interface{}{"type": "queued", "rating": store.Default.Rating(name), "size": size})
+	case "unqueue":
+		if mm.dequeue(c) {
+			c.reply(map[string]interface{}{"type": "unqueued"})
+		}
+	default:
+		// ignore unknown types for now
+	}
+	return true
+}
+
+// joinError is the answer to a refused join, with the code of the game.JoinError.
+func joinError(err error) map[string]interface{} {
+	code := "join_failed"
+	if je, ok := err.(*game.JoinError); ok {
+		code = je.Code
 	}
+	return map[string]interface{}{"type": "error", "code": code, "message": err.Error()}
+}
+
+// joinAck is the answer to a successful join in room.
+func joinAck(room *Room, p *game.Player) map[string]interface{} {
+	g := room.Game()
+	ack := map[string]interface{}{"type": "join_ack", "id": p.ID, "room": room.ID, "pos": map[string]int{"x": p.X, "y": p.Y}, "grid": map[string]int{"w": g.W, "h": g.H}}
+	if walls := g.Walls(); len(walls) > 0 {
+		ack["walls"] = walls
+	}
+	if profile, ok := store.Default.Get(p.Name); ok {
+		ack["profile"] = profile
+	}
+	if m := motd(); m != "" {
+		ack["motd"] = m
+	}
+	return ack
 }
 
 // writePump writes messages from the send channel to the websocket connection.
+// It owns the end of the connection: once send is closed it sends the close frame, if any, and closes it.
 func (c *Client) writePump() {
 	for msg := range c.send {
+		c.conn.SetWriteDeadline(time.Now().Add(writeWait)) // a stuck peer must not block us forever
 		err := c.conn.WriteMessage(websocket.TextMessage, msg)
 		if err != nil {
-			log.Println("[WS] write error:", err)
+			c.logger().Warn("write error", "err", err)
 			break
 		}
+		messageSize.With("out").Observe(float64(len(msg)))
+		bytesSent.Add(uint64(len(msg)))
+	}
+	c.mu.Lock()
+	closing := c.closing
+	c.mu.Unlock()
+	if closing != nil {
+		c.conn.WriteControl(websocket.CloseMessage, closing, time.Now().Add(time.Second))
 	}
 	c.conn.Close()
 }
 
+// writeWait is the time allowed to write a message to a client.
+const writeWait = 10 * time.Second
+
 // WS upgrades the HTTP connection to a WebSocket and registers the client.
+// With a token (see auth.go) the player is bound to its account.
 func WS(w http.ResponseWriter, r *http.Request) {
+	// check the token before upgrading, so a bad token gets a plain 401
+	account, err := authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if _, ok := banned(account); account != "" && ok {
+		http.Error(w, "banned", http.StatusForbidden)
+		return
+	}
+	// admission control: refuse with 503/429 before upgrading (see admission.go)
+	room := getRoom(DefaultRoom)
+	if roomFull(room) {
+		refuse(w, http.StatusServiceUnavailable, "room full")
+		return
+	}
+	ip := clientIP(r)
+	if status, msg := adm.admit(ip); status != 0 {
+		slog.Warn("connection refused", "remote", ip, "status", status, "reason", msg)
+		refuse(w, status, msg)
+		return
+	}
+	defer adm.release(ip)
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Println("[WS] upgrade:", err)
+		slog.Warn("upgrade failed", "remote", ip, "err", err)
 		return
 	}
-	client := &Client{conn: conn, send: make(chan []byte, 256)}
-	h.register <- client
+	client := &Client{conn: conn, send: make(chan []byte, SendBuffer), room: room, account: account, remote: conn.RemoteAddr().String()}
+	client.logger().Info("client connected")
+	room.enter(client) // wakes the room up if it sleeps
 	go client.writePump()
 	client.readPump()
 }
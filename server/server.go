@@ -3,6 +3,7 @@ package server
 import (
 	"net/http"
 
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/metrics"
 	"github.com/dntelisa/SR-S9-Projet-Serveur/server/routes"
 )
 
@@ -10,4 +11,33 @@ import (
 func SetupRoutes() {
 	http.HandleFunc("/", routes.Root)
 	http.HandleFunc("/ws", routes.WS)
+	// probes for the load balancer and deployment scripts, and the load of the game loops (see routes/health.go)
+	http.HandleFunc("GET /healthz", routes.Healthz)
+	http.HandleFunc("GET /readyz", routes.Readyz)
+	http.HandleFunc("GET /info", routes.Info)
+	http.HandleFunc("GET /health", routes.Health)
+	// Prometheus metrics (see server/metrics)
+	http.HandleFunc("GET /metrics", metrics.Default.Handler())
+	// read-only REST API (leaderboard, profiles, live scores)
+	http.HandleFunc("GET /api/leaderboard", routes.Leaderboard)
+	http.HandleFunc("GET /api/players/{name}", routes.PlayerStats)
+	http.HandleFunc("GET /api/rooms/{id}/scores", routes.RoomScores)
+	// Server-Sent Events transport, for the clients whose proxy breaks WebSockets (see routes/sse.go)
+	http.HandleFunc("GET /api/rooms/{id}/events", routes.Events)
+	http.HandleFunc("POST /api/rooms/{id}/input", routes.Input)
+	// accounts and tokens (see routes/auth.go)
+	http.HandleFunc("POST /api/login", routes.Login)
+	http.HandleFunc("POST /api/register", routes.Register)
+	// admin API, needs -admin-token (see routes/admin.go)
+	http.HandleFunc("GET /api/admin/anticheat", routes.CheatReport)
+	http.HandleFunc("GET /api/admin/rooms", routes.AdminRooms)
+	http.HandleFunc("GET /api/admin/rooms/{id}", routes.AdminRoom)
+	http.HandleFunc("POST /api/admin/rooms/{id}/{action}", routes.AdminRoomAction)
+	http.HandleFunc("POST /api/admin/rooms/{id}/sweets", routes.AdminSweets)
+	http.HandleFunc("DELETE /api/admin/rooms/{id}/sweets", routes.AdminSweets)
+	http.HandleFunc("POST /api/admin/rooms/{id}/placement", routes.AdminPlacement)
+	http.HandleFunc("POST /api/admin/rooms/{id}/players/{player}/kick", routes.AdminKick)
+	http.HandleFunc("GET /api/admin/bans", routes.AdminBans)
+	http.HandleFunc("POST /api/admin/bans", routes.AdminBans)
+	http.HandleFunc("DELETE /api/admin/bans/{name}", routes.AdminUnban)
 }
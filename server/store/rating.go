@@ -0,0 +1,57 @@
+package store
+
+import "math"
+
+// DefaultRating is the rating of a new player.
+const DefaultRating = 1500
+
+// ratingK is the Elo K-factor: the most a player can win or lose against one opponent,
+// divided by the number of opponents so that a round weighs the same whatever its size.
+const ratingK = 32
+
+// Rating returns the rating of name (DefaultRating for an unknown player).
+func (s *Store) Rating(name string) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.profiles[name]; ok {
+		return p.Rating
+	}
+	return DefaultRating
+}
+
+// updateRatingsLocked applies a multi-player Elo update: every pair of players of the round
+// is a duel won by the higher score (a tie is a draw). Caller must hold s.mu.
+func (s *Store) updateRatingsLocked(results []Result) {
+	players := make([]Result, 0, len(results))
+	for _, r := range results {
+		if r.Name != "" {
+			players = append(players, r)
+		}
+	}
+	if len(players) < 2 {
+		return // nothing to compare
+	}
+	k := float64(ratingK) / float64(len(players)-1)
+	deltas := make([]float64, len(players))
+	for i, a := range players {
+		ra := s.profileLocked(a.Name).Rating
+		for j, b := range players {
+			if i == j {
+				continue
+			}
+			rb := s.profileLocked(b.Name).Rating
+			expected := 1 / (1 + math.Pow(10, (rb-ra)/400))
+			actual := 0.5
+			if a.Score > b.Score {
+				actual = 1
+			} else if a.Score < b.Score {
+				actual = 0
+			}
+			deltas[i] += k * (actual - expected)
+		}
+	}
+	// apply after computing every delta, so the order of players does not matter
+	for i, a := range players {
+		s.profileLocked(a.Name).Rating += deltas[i]
+	}
+}
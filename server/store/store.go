@@ -0,0 +1,273 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Stats are cumulative statistics over a set of rounds.
+type Stats struct {
+	Rounds    int `json:"rounds"`     // rounds played until game_over
+	Wins      int `json:"wins"`       // rounds finished with the best score (ties all win)
+	Sweets    int `json:"sweets"`     // total sweets collected
+	BestScore int `json:"best_score"` // best score in a single round
+}
+
+// Profile holds the statistics of a player, kept across rounds and restarts:
+// all-time, per day (last DaysKept days) and per room.
+type Profile struct {
+	Name string `json:"name"`
+	Stats
+	Rating   float64           `json:"rating"` // Elo rating, see rating.go
+	LastSeen time.Time         `json:"last_seen"`
+	Daily    map[string]*Stats `json:"daily,omitempty"` // key: UTC day, "2006-01-02"
+	Rooms    map[string]*Stats `json:"rooms,omitempty"` // key: room ID
+}
+
+// DaysKept is the number of days of daily statistics kept in a profile.
+const DaysKept = 30
+
+// DayKey returns the key of t in Profile.Daily.
+func DayKey(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+func (st *Stats) add(score int, win bool) {
+	st.Rounds++
+	st.Sweets += score
+	st.BestScore = max(st.BestScore, score)
+	if win {
+		st.Wins++
+	}
+}
+
+// Result is the score of a player at the end of a round.
+type Result struct {
+	Name  string
+	Score int
+}
+
+// Store is a file-backed set of profiles keyed by player name.
+// The whole file is rewritten after each round, which is fine for a few thousand players.
+type Store struct {
+	mu       sync.Mutex
+	path     string // empty: in memory only
+	profiles map[string]*Profile
+}
+
+// file is the on-disk format.
+type file struct {
+	Profiles map[string]*Profile `json:"profiles"`
+}
+
+// New returns an in-memory store (nothing is written to disk).
+func New() *Store {
+	return &Store{profiles: make(map[string]*Profile)}
+}
+
+// Open loads the store from path, creating it on the first save if it does not exist.
+func Open(path string) (*Store, error) {
+	s := New()
+	s.path = path
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, err
+	}
+	if f.Profiles != nil {
+		s.profiles = f.Profiles
+	}
+	for _, p := range s.profiles {
+		if p.Rating == 0 {
+			p.Rating = DefaultRating // file written before ratings existed
+		}
+	}
+	return s, nil
+}
+
+// Default is the store used by the server, in memory unless main opens a file.
+var Default = New()
+
+// RecordRound updates the profiles of every player of a round finished in room, and saves the store.
+func (s *Store) RecordRound(room string, results []Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	best := 0
+	for _, r := range results {
+		best = max(best, r.Score)
+	}
+	s.updateRatingsLocked(results)
+	now := time.Now()
+	day := DayKey(now)
+	oldest := DayKey(now.AddDate(0, 0, -DaysKept+1))
+	for _, r := range results {
+		if r.Name == "" {
+			continue
+		}
+		win := r.Score == best && best > 0
+		p := s.profileLocked(r.Name)
+		p.Stats.add(r.Score, win)
+		p.LastSeen = now
+		if p.Daily == nil {
+			p.Daily = make(map[string]*Stats)
+		}
+		if p.Daily[day] == nil {
+			p.Daily[day] = &Stats{}
+		}
+		p.Daily[day].add(r.Score, win)
+		for d := range p.Daily {
+			if d < oldest {
+				delete(p.Daily, d)
+			}
+		}
+		if room != "" {
+			if p.Rooms == nil {
+				p.Rooms = make(map[string]*Stats)
+			}
+			if p.Rooms[room] == nil {
+				p.Rooms[room] = &Stats{}
+			}
+			p.Rooms[room].add(r.Score, win)
+		}
+	}
+	return s.saveLocked()
+}
+
+// Get returns a copy of the profile of name.
+func (s *Store) Get(name string) (Profile, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.profiles[name]
+	if !ok {
+		return Profile{}, false
+	}
+	return p.copy(), true
+}
+
+// All returns a copy of every profile, sorted by name.
+func (s *Store) All() []Profile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all := make([]Profile, 0, len(s.profiles))
+	for _, p := range s.profiles {
+		all = append(all, p.copy())
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
+
+// Entry is a line of a leaderboard.
+type Entry struct {
+	Rank int    `json:"rank"`
+	Name string `json:"name"`
+	Stats
+	Rating float64 `json:"rating"`
+}
+
+// Leaderboard ranks players by wins, then sweets collected, then best score.
+// day (see DayKey) restricts it to the rounds of that day and room to the rounds of that room;
+// empty strings mean all-time and every room. At most limit entries are returned (0: no limit).
+func (s *Store) Leaderboard(day, room string, limit int) []Entry {
+	s.mu.Lock()
+	entries := make([]Entry, 0, len(s.profiles))
+	for _, p := range s.profiles {
+		st := &p.Stats
+		switch {
+		case day != "":
+			st = p.Daily[day]
+		case room != "":
+			st = p.Rooms[room]
+		}
+		if st == nil || st.Rounds == 0 {
+			continue
+		}
+		entries = append(entries, Entry{Name: p.Name, Stats: *st, Rating: p.Rating})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		if a.Sweets != b.Sweets {
+			return a.Sweets > b.Sweets
+		}
+		if a.BestScore != b.BestScore {
+			return a.BestScore > b.BestScore
+		}
+		return a.Name < b.Name
+	})
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	return entries
+}
+
+// copy returns a deep copy of p, safe to use outside the lock.
+func (p *Profile) copy() Profile {
+	cp := *p
+	cp.Daily = copyStats(p.Daily)
+	cp.Rooms = copyStats(p.Rooms)
+	return cp
+}
+
+func copyStats(m map[string]*Stats) map[string]*Stats {
+	if m == nil {
+		return nil
+	}
+	cp := make(map[string]*Stats, len(m))
+	for k, v := range m {
+		st := *v
+		cp[k] = &st
+	}
+	return cp
+}
+
+func (s *Store) profileLocked(name string) *Profile {
+	p, ok := s.profiles[name]
+	if !ok {
+		p = &Profile{Name: name, Rating: DefaultRating}
+		s.profiles[name] = p
+	}
+	return p
+}
+
+// saveLocked writes the store to a temporary file then renames it, so a crash never leaves a half-written file.
+func (s *Store) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	b, err := json.Marshal(file{Profiles: s.profiles})
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".store-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
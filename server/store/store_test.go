@@ -0,0 +1,120 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordRound(t *testing.T) {
+	s := New()
+	s.RecordRound("default", []Result{{"Alice", 5}, {"Bob", 3}})
+	s.RecordRound("default", []Result{{"Alice", 2}, {"Bob", 4}, {"", 9}})
+
+	a, ok := s.Get("Alice")
+	if !ok {
+		t.Fatalf("Alice has no profile")
+	}
+	if a.Rounds != 2 || a.Wins != 1 || a.Sweets != 7 || a.BestScore != 5 {
+		t.Fatalf("unexpected profile %+v", a)
+	}
+	b, _ := s.Get("Bob")
+	// the unnamed player has the best score of round 2: nobody with a profile wins it
+	if b.Rounds != 2 || b.Wins != 0 || b.Sweets != 7 || b.BestScore != 4 {
+		t.Fatalf("unexpected profile %+v", b)
+	}
+	if _, ok := s.Get(""); ok {
+		t.Fatalf("unnamed players must not get a profile")
+	}
+}
+
+func TestTiesAndEmptyRounds(t *testing.T) {
+	s := New()
+	s.RecordRound("default", []Result{{"A", 3}, {"B", 3}, {"C", 1}})
+	s.RecordRound("default", []Result{{"A", 0}, {"B", 0}}) // nobody collected anything: no winner
+	for _, name := range []string{"A", "B"} {
+		if p, _ := s.Get(name); p.Wins != 1 || p.Rounds != 2 {
+			t.Fatalf("unexpected profile %+v", p)
+		}
+	}
+	if p, _ := s.Get("C"); p.Wins != 0 {
+		t.Fatalf("C should not win: %+v", p)
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "players.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("open new store: %v", err)
+	}
+	if err := s.RecordRound("default", []Result{{"Alice", 5}}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	s2, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	p, ok := s2.Get("Alice")
+	if !ok || p.Rounds != 1 || p.BestScore != 5 {
+		t.Fatalf("profile not persisted: %+v", p)
+	}
+	if len(s2.All()) != 1 {
+		t.Fatalf("expected 1 profile, got %v", s2.All())
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	s := New()
+	s.RecordRound("r1", []Result{{"A", 5}, {"B", 3}})
+	s.RecordRound("r2", []Result{{"B", 4}, {"C", 1}})
+	s.RecordRound("r2", []Result{{"B", 2}, {"C", 6}})
+
+	all := s.Leaderboard("", "", 0)
+	// B: 1 win, 9 sweets; C: 1 win, 7 sweets; A: 1 win, 5 sweets
+	if len(all) != 3 || all[0].Name != "B" || all[1].Name != "C" || all[2].Name != "A" || all[2].Rank != 3 {
+		t.Fatalf("unexpected all-time leaderboard %+v", all)
+	}
+	r2 := s.Leaderboard("", "r2", 0)
+	if len(r2) != 2 || r2[0].Name != "C" || r2[1].Name != "B" || r2[1].Rounds != 2 {
+		t.Fatalf("unexpected room leaderboard %+v", r2)
+	}
+	today := s.Leaderboard(DayKey(time.Now()), "", 1)
+	if len(today) != 1 || today[0].Name != "B" {
+		t.Fatalf("unexpected daily leaderboard %+v", today)
+	}
+	if old := s.Leaderboard("2000-01-01", "", 0); len(old) != 0 {
+		t.Fatalf("expected empty leaderboard for another day, got %+v", old)
+	}
+}
+
+func TestRatings(t *testing.T) {
+	s := New()
+	if r := s.Rating("new"); r != DefaultRating {
+		t.Fatalf("expected default rating, got %v", r)
+	}
+	s.RecordRound("r", []Result{{"A", 5}, {"B", 3}, {"C", 3}})
+	a, b, c := s.Rating("A"), s.Rating("B"), s.Rating("C")
+	if !(a > DefaultRating && b < DefaultRating && b == c) {
+		t.Fatalf("unexpected ratings A=%v B=%v C=%v", a, b, c)
+	}
+	// zero-sum between equally rated players
+	if sum := a + b + c; sum < 3*DefaultRating-1e-9 || sum > 3*DefaultRating+1e-9 {
+		t.Fatalf("ratings should sum to %d, got %v", 3*DefaultRating, sum)
+	}
+	// a solo round does not change the rating
+	s.RecordRound("r", []Result{{"A", 9}})
+	if s.Rating("A") != a {
+		t.Fatalf("solo round changed the rating")
+	}
+	// beating a stronger player is worth more than beating a weaker one
+	before := s.Rating("B")
+	s.RecordRound("r", []Result{{"B", 2}, {"A", 1}})
+	gainVsStrong := s.Rating("B") - before
+	s2 := New()
+	s2.RecordRound("r", []Result{{"X", 1}, {"Y", 0}})
+	if gainVsStrong <= s2.Rating("X")-DefaultRating {
+		t.Fatalf("upset gain %v should exceed even gain %v", gainVsStrong, s2.Rating("X")-DefaultRating)
+	}
+}
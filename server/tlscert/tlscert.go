@@ -0,0 +1,121 @@
+// Package tlscert serves the TLS certificate of the server from files, read again when
+// they change (e.g. renewed by certbot) so that wss:// clients never see an expired one,
+// and redirects plain HTTP to HTTPS.
+package tlscert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Reloader holds the certificate of a cert/key pair of PEM files.
+type Reloader struct {
+	certFile, keyFile string
+	mu                sync.Mutex
+	cert              *tls.Certificate
+	stamp             string // modification times and sizes of the files at the last load
+}
+
+// Load reads the certificate and its key.
+func Load(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	r.cert, r.stamp = &cert, r.files()
+	return r, nil
+}
+
+// GetCertificate is the tls.Config callback: the handshakes get the last certificate loaded.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cert, nil
+}
+
+// TLSConfig returns a server config using the certificate of r.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{GetCertificate: r.GetCertificate, MinVersion: tls.VersionTLS12}
+}
+
+// files describes the state of the files, "" if one is missing.
+func (r *Reloader) files() string {
+	stamp := ""
+	for _, name := range []string{r.certFile, r.keyFile} {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return ""
+		}
+		stamp += fi.ModTime().String() + "/" + strconv.FormatInt(fi.Size(), 10) + ";"
+	}
+	return stamp
+}
+
+// Check loads the files again if they changed since the last load. A pair that does not
+// load (e.g. the new certificate written but not yet its key) is logged and the previous
+// certificate kept; it is tried again when the files change.
+func (r *Reloader) Check() {
+	stamp := r.files()
+	r.mu.Lock()
+	if stamp == "" || stamp == r.stamp {
+		r.mu.Unlock()
+		return
+	}
+	r.stamp = stamp
+	r.mu.Unlock()
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		slog.Warn("certificate not reloaded, previous one kept", "cert", r.certFile, "err", err)
+		return
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.mu.Unlock()
+	args := []any{"cert", r.certFile}
+	if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil {
+		args = append(args, "subject", leaf.Subject.String(), "not_after", leaf.NotAfter)
+	}
+	slog.Info("certificate reloaded", args...)
+}
+
+// Watch checks the files every interval until stop is called.
+func (r *Reloader) Watch(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				r.Check()
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// Redirect answers every request with a permanent redirect to the same URL over HTTPS,
+// on the port of httpsAddr (the address the TLS server listens on).
+func Redirect(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		host := req.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, req, "https://"+host+req.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
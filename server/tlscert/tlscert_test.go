@@ -0,0 +1,155 @@
+package tlscert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/routes"
+	"github.com/gorilla/websocket"
+)
+
+var renewals int
+
+// selfSigned writes a new self-signed certificate for 127.0.0.1 and its key, and
+// returns a pool trusting it.
+func selfSigned(t *testing.T, certFile, keyFile, name string) *x509.CertPool {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	// a new modification time at each renewal, even on a coarse clock
+	renewals++
+	later := time.Now().Add(time.Duration(renewals) * time.Minute)
+	os.Chtimes(certFile, later, later)
+	os.Chtimes(keyFile, later, later)
+	leaf, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return pool
+}
+
+func TestWSS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	first := selfSigned(t, certFile, keyFile, "first")
+	certs, err := Load(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := game.NewGame(6, 6, 0)
+	defer routes.SetDefaultGame(game.Default)
+	routes.SetDefaultGame(g)
+	if !g.Running() {
+		g.Start(100)
+	}
+	defer g.Stop()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", routes.WS)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: mux, TLSConfig: certs.TLSConfig()}
+	go srv.ServeTLS(ln, "", "")
+	defer srv.Close()
+	url := "wss://" + ln.Addr().String() + "/ws"
+
+	dial := func(pool *x509.CertPool) (*websocket.Conn, error) {
+		d := websocket.Dialer{TLSClientConfig: &tls.Config{RootCAs: pool}, HandshakeTimeout: time.Second}
+		c, _, err := d.Dial(url, nil)
+		return c, err
+	}
+	c, err := dial(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.WriteJSON(map[string]interface{}{"type": "join", "name": "Secure"})
+	c.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		var m map[string]interface{}
+		if err := c.ReadJSON(&m); err != nil {
+			t.Fatalf("no join_ack over wss: %v", err)
+		}
+		if m["type"] == "join_ack" {
+			break
+		}
+		if m["type"] == "error" {
+			t.Fatalf("join refused: %v", m)
+		}
+	}
+	c.Close()
+	if _, err := dial(nil); err == nil {
+		t.Fatal("untrusted certificate accepted")
+	}
+
+	// renewed certificate: served once the files are checked
+	second := selfSigned(t, certFile, keyFile, "second")
+	certs.Check()
+	if _, err := dial(first); err == nil {
+		t.Fatal("old certificate still served")
+	}
+	c, err = dial(second)
+	if err != nil {
+		t.Fatalf("renewed certificate not served: %v", err)
+	}
+	c.Close()
+
+	// a broken pair keeps the previous certificate
+	os.WriteFile(keyFile, []byte("not a key"), 0o600)
+	certs.Check()
+	if c, err = dial(second); err != nil {
+		t.Fatalf("certificate lost on a broken reload: %v", err)
+	}
+	c.Close()
+}
+
+func TestRedirect(t *testing.T) {
+	for addr, want := range map[string]string{
+		":8443":          "https://jeu.example:8443/ws?room=a",
+		"0.0.0.0:443":    "https://jeu.example/ws?room=a",
+		"localhost:9443": "https://jeu.example:9443/ws?room=a",
+	} {
+		w := httptest.NewRecorder()
+		Redirect(addr).ServeHTTP(w, httptest.NewRequest("GET", "http://jeu.example:8080/ws?room=a", nil))
+		if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != want {
+			t.Fatalf("%s: got %d to %q, expected %q", addr, w.Code, w.Header().Get("Location"), want)
+		}
+	}
+}
@@ -2,19 +2,178 @@ package main
 
 import (
 	"flag" // library for command-line flag parsing
-	"log" // print logs
+	"fmt"
+	"log/slog" // structured logs (see server/logging)
 	"net/http" // HTTP server
+	"os"
+	"time"
 
 	"github.com/dntelisa/SR-S9-Projet-Serveur/server"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/auth"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/config"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/game"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/logging"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/routes"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/store"
+	"github.com/dntelisa/SR-S9-Projet-Serveur/server/tlscert"
 )
 
-// addr var is the address to listen on, default localhost:8080
-var addr = flag.String("addr", "localhost:8080", "http service address")
+// settings: defaults < config file (-config) < $SUPERSERVEUR_* < flags (see server/config)
+var flags = config.Register(flag.CommandLine)
+
+// apiKey var is a command: create an API key for this account name, print it and exit
+var apiKey = flag.String("apikey", "", "create an API key for this account name, print it and exit")
+
+// reloadEvery is how often the config file is checked for changes (SIGHUP reloads it at once)
+const reloadEvery = 2 * time.Second
+
+// fatal logs err and exits.
+func fatal(err error) {
+	slog.Error(err.Error())
+	os.Exit(1)
+}
+
+// rules returns the rules of a round set by cfg.
+func rules(cfg *config.Config) game.Rules {
+	return game.Rules{Sweets: cfg.Game.Sweets, MaxMoves: cfg.Game.MaxMoves, Intermission: cfg.Game.Intermission}
+}
+
+// cells converts the cells of the map settings (checked by the config).
+func cells(list []string) []game.Pos {
+	pos := make([]game.Pos, 0, len(list))
+	for _, c := range list {
+		x, y, _ := config.Cell(c)
+		pos = append(pos, game.Pos{X: x, Y: y})
+	}
+	return pos
+}
+
+// applyLive applies the settings a config reload may change, at startup and at each reload.
+// The block-list is read first: if it cannot be, nothing is applied.
+func applyLive(cfg *config.Config) error {
+	var words []string
+	if cfg.Files.Blocklist != "" {
+		f, err := os.Open(cfg.Files.Blocklist)
+		if err != nil {
+			return err
+		}
+		words, err = game.ReadBlockList(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	routes.SetLive(routes.Live{
+		MOTD:           cfg.MOTD,
+		Rules:          rules(cfg),
+		Origins:        cfg.Net.Origins,
+		MaxConns:       cfg.Net.MaxConns,
+		MaxConnsPerIP:  cfg.Net.MaxConnsPerIP,
+		MaxRoomPlayers: cfg.Game.MaxPlayers,
+		MsgRate:        cfg.Net.MsgRate,
+		MsgBurst:       cfg.Net.MsgBurst,
+		RoomIdle:       cfg.Game.RoomIdle,
+		AdminToken:     cfg.Auth.AdminToken,
+		Bans:           cfg.Auth.Bans,
+		MatchSize:      cfg.Match.Size,
+		MatchWindow:    cfg.Match.Window,
+		MatchWiden:     cfg.Match.Widen,
+	})
+	game.SetBlockList(words)
+	if cfg.Files.Blocklist != "" {
+		slog.Info("name block-list loaded", "words", len(words))
+	}
+	return nil
+}
 
 func main() {
 	flag.Parse() // address entry in terminal to replace default
-	log.Println("[INFO] SUPERSERVEUR")
-	log.Println("[INFO] Waiting for requests...")
+	cfg, err := flags.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := logging.Setup(os.Stderr, cfg.Log.Level, cfg.Log.Format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	routes.LogSample = cfg.Log.Sample
+	slog.Info("SUPERSERVEUR")
+	if flags.Path() != "" {
+		slog.Info("config loaded", "file", flags.Path())
+	}
+	if cfg.Files.DB != "" {
+		s, err := store.Open(cfg.Files.DB)
+		if err != nil {
+			fatal(err)
+		}
+		store.Default = s
+	}
+	if cfg.Files.Accounts != "" {
+		a, err := auth.Open(cfg.Files.Accounts)
+		if err != nil {
+			fatal(err)
+		}
+		auth.Default = a
+	}
+	if *apiKey != "" {
+		key, err := auth.Default.NewAPIKey(*apiKey)
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Println(key)
+		return
+	}
+	auth.Tokens = auth.NewSigner([]byte(cfg.Auth.Secret))
+	routes.RequireAuth = cfg.Auth.Required
+	routes.SendBuffer = cfg.Net.SendBuffer
+	game.BroadcastBuffer = cfg.Net.BroadcastBuffer
+	// games of the rooms, the default one included
+	routes.GridW, routes.GridH, routes.TickRate, routes.MinTPS = cfg.Game.Width, cfg.Game.Height, cfg.Game.TPS, cfg.Game.MinTPS
+	routes.RoomWalls, routes.RoomSpawns = cells(cfg.Map.Walls), cells(cfg.Map.Spawns)
+	routes.RoomPlacement = game.SweetPlacement{Strategy: cfg.Map.Placement, MinSpacing: cfg.Map.Spacing, Regions: cfg.Map.Regions, Clusters: cfg.Map.Clusters}
+	// the live settings first: the rules of the rooms are among them
+	if err := applyLive(cfg); err != nil {
+		fatal(err)
+	}
+	g := routes.NewGame()
+	g.SetAdaptiveTPS(cfg.Game.MinTPS)
+	routes.SetDefaultGame(g)
+	if cfg.Files.Record != "" {
+		f, err := os.Create(cfg.Files.Record)
+		if err != nil {
+			fatal(err)
+		}
+		if err := game.Default.StartRecording(f); err != nil {
+			fatal(err)
+		}
+		slog.Info("recording match", "file", cfg.Files.Record)
+	}
+	if cfg.Game.Endless > 0 {
+		mode := &game.EndlessMode{RespawnDelay: cfg.Ticks(cfg.Game.Respawn), RoundDuration: cfg.Ticks(cfg.Game.Endless)}
+		if err := game.Default.SetEndless(mode); err != nil {
+			fatal(err)
+		}
+		slog.Info("endless mode", "round", cfg.Game.Endless)
+	}
+	slog.Info("waiting for requests", "addr", cfg.Addr, "tls", cfg.TLS.Cert != "", "grid", fmt.Sprintf("%dx%d", cfg.Game.Width, cfg.Game.Height), "tps", cfg.Game.TPS)
+	// config reload: the live settings change, the connections stay
+	flags.Watch(cfg, reloadEvery, func(old, cfg *config.Config) error { return applyLive(cfg) })
 	server.SetupRoutes()
-	log.Fatal(http.ListenAndServe(*addr, nil))
+	go routes.CollectRooms() // removes the empty matched rooms once RoomIdle has passed
+	if cfg.TLS.Cert == "" {
+		fatal(http.ListenAndServe(cfg.Addr, nil))
+	}
+	// TLS: HTTPS and wss:// only, the certificate is read again when its files change
+	certs, err := tlscert.Load(cfg.TLS.Cert, cfg.TLS.Key)
+	if err != nil {
+		fatal(err)
+	}
+	certs.Watch(reloadEvery)
+	if cfg.TLS.Redirect != "" {
+		slog.Info("redirecting to HTTPS", "addr", cfg.TLS.Redirect)
+		go func() { fatal(http.ListenAndServe(cfg.TLS.Redirect, tlscert.Redirect(cfg.Addr))) }()
+	}
+	srv := &http.Server{Addr: cfg.Addr, TLSConfig: certs.TLSConfig()}
+	fatal(srv.ListenAndServeTLS("", ""))
 }